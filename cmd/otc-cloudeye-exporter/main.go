@@ -1,29 +1,37 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
-	"fmt"
+	"io/fs"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	"github.com/abdo-farag/otc-cloudeye-exporter/internal/clients"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/collector"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/grafana"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/registry"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/server"
 )
 
 // Global state for health checks
 var (
-	isReady   int32 // 0 = not ready, 1 = ready
-	startTime time.Time
+	isReady int32 // 0 = not ready, 1 = ready
+	// isDraining is set when shutdown starts, so readiness fails before the listeners close
+	isDraining int32
+	startTime  time.Time
 )
 
 // HealthStatus represents the health check response
@@ -34,71 +42,168 @@ type HealthStatus struct {
 	Checks    map[string]string `json:"checks,omitempty"`
 }
 
-// parseNamespaces splits a comma-separated list of namespaces into a slice.
-func parseNamespaces(ns string) []string {
+// parseNamespaces resolves a comma-separated list of namespaces and "@category" selectors.
+func parseNamespaces(ns string) ([]string, error) {
 	if ns == "" {
-		return nil
+		return nil, nil
 	}
-	return strings.Split(ns, ",")
+	return registry.Expand(ns)
 }
 
-// loadConfigs loads global and endpoint configs.
-func loadConfigs(globalConfigPath, endpointConfigPath string) (*config.Config, *config.EndpointConfig, error) {
-	cfg, err := config.LoadConfig(globalConfigPath)
+// configFileOrNone returns the config file to load. When -config is not given and
+// the default clouds.yml does not exist, it returns "" to run from flags and
+// environment variables only.
+func configFileOrNone(flags *flag.FlagSet, path string) string {
+	explicit := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+	if !explicit {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			logs.Infof("No %s found; using flags and %s* environment variables only", path, config.EnvPrefix)
+			return ""
+		}
+	}
+	return path
+}
+
+// loadConfigs loads the global config and the optional endpoint overrides.
+func loadConfigs(globalConfigPath string, overrides *config.Overrides) (*config.Config, *config.EndpointConfig, error) {
+	cfg, err := config.LoadConfig(globalConfigPath, overrides)
 	if err != nil {
 		return nil, nil, err
 	}
-	endpointCfg, err := config.LoadEndpointConfig(endpointConfigPath)
+	// endpoints.yml is optional unless its path is set explicitly
+	endpointConfigPath := cfg.Global.EndpointsConfPath
+	required := endpointConfigPath != ""
+	if !required {
+		endpointConfigPath = constants.DefaultEndpointsConfPath
+	}
+	endpointCfg, err := config.LoadEndpointConfig(endpointConfigPath, required)
 	if err != nil {
 		return nil, nil, err
 	}
+	if cfg.Global.EndpointDiscovery {
+		discoverEndpoints(cfg, endpointCfg)
+	}
 	return cfg, endpointCfg, nil
 }
 
-// getServiceEndpoints builds a namespace->endpoint map.
-func getServiceEndpoints(parsedNamespaces []string, endpointCfg *config.EndpointConfig) map[string]string {
-	serviceEndpoints := make(map[string]string)
-	for key, url := range endpointCfg.Services {
-		// Replace {region} with actual region string!
-		endpoint := strings.ReplaceAll(url, "{region}", endpointCfg.Region)
-		serviceEndpoints[key] = endpoint
+// discoverEndpoints reads the IAM service catalog of every account; static entries still take precedence.
+func discoverEndpoints(cfg *config.Config, endpointCfg *config.EndpointConfig) {
+	for _, account := range cfg.Accounts {
+		for _, region := range account.Regions {
+			discovered, err := config.DiscoverEndpoints(account.ForRegion(region))
+			if err != nil {
+				logs.Warnf("Endpoint discovery failed for account %s region %s: %v", account.Name, region.Name, err)
+				continue
+			}
+			// Every region of the catalog is counted, not only the configured ones
+			services, endpoints := make(map[string]bool), 0
+			for _, byService := range discovered {
+				for service := range byService {
+					services[service] = true
+					endpoints++
+				}
+			}
+			logs.Infof("Discovered %d endpoints of %d services in %d catalog regions from IAM (account %s, queried in region %s)",
+				endpoints, len(services), len(discovered), account.Name, region.Name)
+			endpointCfg.AddDiscovered(discovered)
+			break // the catalog lists all regions of an account
+		}
 	}
-	return serviceEndpoints
 }
 
-// validateProject checks if a project exists in the configured region
-func validateProject(auth *config.CloudAuth, projectName string) error {
-	allProjects, err := config.FetchAllProjects(*auth)
-	if err != nil {
-		logs.Errorf("Error fetching projects for region %s: %v", auth.Region, err)
-		return err
+// resolvedEndpoints returns the effective endpoint map per account and region.
+func resolvedEndpoints(cfg *config.Config, endpointCfg *config.EndpointConfig) map[string]map[string]map[string]string {
+	result := make(map[string]map[string]map[string]string)
+	for _, account := range cfg.Accounts {
+		result[account.Name] = make(map[string]map[string]string)
+		for _, region := range account.Regions {
+			result[account.Name][region.Name] = endpointCfg.ForRegion(region.Name, region.Endpoints).Services
+		}
+	}
+	return result
+}
+
+// endpointsDebugHandler handles the /debug/endpoints endpoint showing the resolved endpoint map.
+func endpointsDebugHandler(states *reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := states.current()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resolvedEndpoints(state.cfg, state.endpointCfg))
+	}
+}
+
+// namespacesHandler handles the /discovery/namespaces endpoint showing the discovered namespaces per project.
+func namespacesHandler(states *reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nsDiscoverer := states.current().nsDiscoverer
+		if nsDiscoverer == nil {
+			http.Error(w, "Namespace discovery is disabled", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(nsDiscoverer.Snapshot())
 	}
-	logs.Infof("Fetched Projects: %v", allProjects)
-	for _, proj := range allProjects {
-		if proj.Name == projectName {
-			return nil // Project found
+}
+
+// warnMissingEndpoints logs namespaces that have no endpoint in a configured region.
+func warnMissingEndpoints(cfg *config.Config, endpointCfg *config.EndpointConfig, namespaces []string) {
+	for _, account := range cfg.Accounts {
+		for _, region := range account.Regions {
+			services := endpointCfg.ForRegion(region.Name, region.Endpoints).Services
+			for _, ns := range namespaces {
+				// Custom namespaces only need the CES endpoint
+				if !registry.IsBuiltin(ns) {
+					continue
+				}
+				if _, ok := services[ns]; !ok {
+					logs.Warnf("No endpoint found for namespace %q in region %s", ns, region.Name)
+				}
+			}
 		}
 	}
-	logs.Errorf("Project %s not found in region %s", projectName, auth.Region)
-	return fmt.Errorf("project %s not found in region %s", projectName, auth.Region)
 }
 
 // prometheusHandler handles the /metrics endpoint logic.
-func prometheusHandler(cfg *config.Config, projectClients []*clients.Clients, defaultNamespaces []string) http.HandlerFunc {
+// Without ?ns=, discovered namespaces (if enabled) take precedence over the static list.
+func prometheusHandler(states *reloader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		state := states.current()
+		cfg, pool, defaultNamespaces, nsDiscoverer := state.cfg, state.pool, state.namespaces, state.nsDiscoverer
 		var namespaces []string
+		requested := false
 		if ns := r.URL.Query().Get("ns"); ns != "" {
-			namespaces = strings.Split(ns, ",")
+			var err error
+			if namespaces, err = parseNamespaces(ns); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			requested = true
 			logs.Infof("Requested namespaces: %v", namespaces)
+		} else if nsDiscoverer != nil {
+			logs.Infof("Using discovered namespaces (static fallback: %v)", defaultNamespaces)
 		} else {
 			namespaces = defaultNamespaces
 			logs.Infof("Using static namespaces: %v", namespaces)
 		}
 
 		reg := prometheus.NewRegistry()
+		collector.RegisterSelfMetrics(reg)
+		reg.MustRegister(reloadSuccess, reloadTimestamp, insecureSkipVerify)
+		reg.MustRegister(pool)
 		// Register your collectors for each client
-		for _, client := range projectClients {
-			collector := collector.NewCloudEyeCollector(cfg, namespaces)
+		for _, client := range pool.Clients() {
+			clientNamespaces := namespaces
+			if !requested && nsDiscoverer != nil {
+				if clientNamespaces = nsDiscoverer.Namespaces(client); clientNamespaces == nil {
+					clientNamespaces = defaultNamespaces
+				}
+			}
+			collector := collector.NewCloudEyeCollector(cfg, clientNamespaces)
 			collector.AttachClient(client)
 			reg.MustRegister(collector)
 		}
@@ -107,8 +212,10 @@ func prometheusHandler(cfg *config.Config, projectClients []*clients.Clients, de
 }
 
 // grafanaDashboardHandler handles the /dashboard endpoint logic for dashboard preview.
-func grafanaDashboardHandler(cfg *config.Config, projectClients []*clients.Clients) http.HandlerFunc {
+func grafanaDashboardHandler(states *reloader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		state := states.current()
+		cfg, pool := state.cfg, state.pool
 		query := r.URL.Query().Get("ns")
 		if query == "" {
 			http.Error(w, "Missing 'ns' (namespace) parameter", http.StatusBadRequest)
@@ -121,7 +228,7 @@ func grafanaDashboardHandler(cfg *config.Config, projectClients []*clients.Clien
 		}
 		namespace := namespaces[0]
 		var exports []collector.MetricExport
-		for _, client := range projectClients {
+		for _, client := range pool.Clients() {
 			exports = collector.ExportMetricValuesBatch(client, cfg, namespace, client.ProjectName)
 			if len(exports) > 0 {
 				logs.Infof("✅ Exported %d metric values from namespace %s", len(exports), namespace)
@@ -140,8 +247,10 @@ func grafanaDashboardHandler(cfg *config.Config, projectClients []*clients.Clien
 }
 
 // grafanaAlertsHandler handles the /alert endpoint logic for alerts preview.
-func grafanaAlertsHandler(cfg *config.Config, projectClients []*clients.Clients) http.HandlerFunc {
+func grafanaAlertsHandler(states *reloader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		state := states.current()
+		cfg, pool := state.cfg, state.pool
 		query := r.URL.Query().Get("ns")
 		if query == "" {
 			http.Error(w, "Missing 'ns' (namespace) parameter", http.StatusBadRequest)
@@ -155,7 +264,7 @@ func grafanaAlertsHandler(cfg *config.Config, projectClients []*clients.Clients)
 		namespace := namespaces[0]
 
 		var exports []collector.MetricExport
-		for _, client := range projectClients {
+		for _, client := range pool.Clients() {
 			exports = collector.ExportMetricValuesBatch(client, cfg, namespace, client.ProjectName)
 			if len(exports) > 0 {
 				logs.Infof("✅ Exported %d metric values for alerts from namespace %s", len(exports), namespace)
@@ -174,8 +283,9 @@ func grafanaAlertsHandler(cfg *config.Config, projectClients []*clients.Clients)
 }
 
 // healthHandler handles the /health endpoint for Docker and K8s health checks
-func healthHandler(projectClients []*clients.Clients) http.HandlerFunc {
+func healthHandler(states *reloader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		pool := states.current().pool
 		w.Header().Set("Content-Type", "application/json")
 		status := HealthStatus{
 			Status:    "healthy",
@@ -186,7 +296,7 @@ func healthHandler(projectClients []*clients.Clients) http.HandlerFunc {
 		// Basic health checks
 		status.Checks["server"] = "ok"
 		// Check if clients are available (basic connectivity)
-		if len(projectClients) > 0 {
+		if pool.Len() > 0 {
 			status.Checks["clients"] = "ok"
 		} else {
 			status.Checks["clients"] = "no_clients"
@@ -203,9 +313,19 @@ func healthHandler(projectClients []*clients.Clients) http.HandlerFunc {
 }
 
 // readinessHandler handles the /ready endpoint for K8s readiness probes
-func readinessHandler(projectClients []*clients.Clients) http.HandlerFunc {
+func readinessHandler(states *reloader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		pool := states.current().pool
 		w.Header().Set("Content-Type", "application/json")
+		if atomic.LoadInt32(&isDraining) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(HealthStatus{
+				Status:    "not_ready",
+				Timestamp: time.Now(),
+				Checks:    map[string]string{"shutdown": "draining"},
+			})
+			return
+		}
 		if atomic.LoadInt32(&isReady) == 0 {
 			w.WriteHeader(http.StatusServiceUnavailable)
 			json.NewEncoder(w).Encode(HealthStatus{
@@ -222,7 +342,7 @@ func readinessHandler(projectClients []*clients.Clients) http.HandlerFunc {
 		}
 		// Check readiness criteria
 		status.Checks["server"] = "ready"
-		if len(projectClients) > 0 {
+		if pool.Len() > 0 {
 			status.Checks["clients"] = "ready"
 		} else {
 			status.Checks["clients"] = "no_clients"
@@ -254,76 +374,124 @@ func main() {
 	// Initialize start time for uptime tracking
 	startTime = time.Now()
 	// --- Step 0: Initialize logging ---
-	logs.InitLog("logs.yml")
+	logs.InitLog(constants.DefaultLogsConfPath)
+	// "validate" checks the configuration offline and exits
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
 	// --- Step 1: Load config ---
 	var configPath string
-	flag.StringVar(&configPath, "config", "clouds.yml", "Path to the config YAML file")
+	var watchInterval time.Duration
+	var enableReloadEndpoint bool
+	var printEffective bool
+	flag.StringVar(&configPath, "config", "clouds.yml", "Path to the config YAML file or a directory of fragments (\"\" to use flags and environment variables only)")
+	flag.BoolVar(&printEffective, "print-effective-config", false, "Print the merged config with secrets redacted and exit")
+	flag.DurationVar(&watchInterval, "config-watch-interval", constants.DefaultConfigWatchInterval, "How often to check the config files for changes (0 disables)")
+	flag.BoolVar(&enableReloadEndpoint, "enable-reload-endpoint", false, "Enable POST /-/reload to reload the config files")
+	// Every clouds.yml setting can also be given as a flag or OTC_EXPORTER_* environment variable
+	overrides := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
-	cfg, endpointCfg, err := loadConfigs(configPath, "endpoints.yml")
-	if err != nil {
-		logs.Fatalf("Failed to load config: %v", err)
+	configPath = configFileOrNone(flag.CommandLine, configPath)
+	if printEffective {
+		os.Exit(printEffectiveConfig(configPath, overrides))
 	}
-	parsedNamespaces := parseNamespaces(cfg.Global.Namespaces)
-	serviceEndpoints := getServiceEndpoints(parsedNamespaces, endpointCfg)
-	// Log endpoint configuration for each namespace
-	for _, ns := range parsedNamespaces {
-		endpoint, err := endpointCfg.GetServiceEndpoint(ns)
-		if err != nil {
-			logs.Warnf("No endpoint found for namespace %q in endpoints.yml", ns)
-			continue
-		}
-		serviceEndpoints[ns] = endpoint
-	}
-	// --- Step 2: Validate Projects Before Initializing Clients ---
-	for _, project := range cfg.Auth.Projects {
-		if err := validateProject(&cfg.Auth, project.Name); err != nil {
-			logs.Errorf("Skipping project %s: %v", project.Name, err)
-			continue // Skip this project and continue with the next one
-		}
-	}
-	// --- Step 3: Initialize project clients with service endpoints ---
-	projectClients, err := clients.NewClientsWithEndpoints(cfg, &config.EndpointConfig{
-		Region:   endpointCfg.Region,
-		Services: serviceEndpoints,
-	})
+	// --- Step 2: Initialize project clients for every account and region ---
+	states, err := newReloader(configPath, overrides)
 	if err != nil {
-		logs.Fatalf("Failed to initialize OTC clients: %v", err)
+		logs.Errorf("Failed to start: %v", err)
+		logs.FlushLogAndExit(1)
 	}
-	logs.Infof("OTC clients initialized successfully for %d projects", len(projectClients))
+	// SIGTERM/SIGINT drain the server; a second signal exits immediately
+	ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stopSignals()
+	// Reload clouds.yml, endpoints.yml and logs.yml on SIGHUP and on file changes
+	go states.watchSignals(ctx)
+	if watchInterval > 0 {
+		go states.watchFiles(ctx, watchInterval)
+	}
+	cfg, web := states.current().cfg, states.current().web
 	// Mark as ready after successful initialization
 	atomic.StoreInt32(&isReady, 1)
-	// --- Step 4: Register HTTP endpoints ---
-	http.HandleFunc(cfg.Global.MetricPath, prometheusHandler(cfg, projectClients, parsedNamespaces))
-	http.HandleFunc("/dashboards", grafanaDashboardHandler(cfg, projectClients))
-	http.HandleFunc("/alerts", grafanaAlertsHandler(cfg, projectClients))
+	// --- Step 3: Register HTTP endpoints ---
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.Global.MetricPath, prometheusHandler(states))
+	mux.HandleFunc("/dashboards", grafanaDashboardHandler(states))
+	mux.HandleFunc("/alerts", grafanaAlertsHandler(states))
+	mux.HandleFunc("/debug/endpoints", endpointsDebugHandler(states))
+	mux.HandleFunc("/discovery/namespaces", namespacesHandler(states))
+	if enableReloadEndpoint {
+		mux.HandleFunc("/-/reload", reloadHandler(states))
+	}
 	// Kubernetes-standard health check endpoints
-	http.HandleFunc("/health", healthHandler(projectClients))
-	http.HandleFunc("/healthz", healthHandler(projectClients))
-	http.HandleFunc("/ready", readinessHandler(projectClients))
-	http.HandleFunc("/readyz", readinessHandler(projectClients))
-	http.HandleFunc("/live", livenessHandler())
-	http.HandleFunc("/livez", livenessHandler())
-	// --- Step 5: Start Server ---
+	mux.HandleFunc("/health", healthHandler(states))
+	mux.HandleFunc("/healthz", healthHandler(states))
+	mux.HandleFunc("/ready", readinessHandler(states))
+	mux.HandleFunc("/readyz", readinessHandler(states))
+	mux.HandleFunc("/live", livenessHandler())
+	mux.HandleFunc("/livez", livenessHandler())
+	// --- Step 4: Start Server ---
 	logs.Infof("📡 Prometheus metrics at: %s?ns=%s", cfg.Global.MetricPath, cfg.Global.Namespaces)
 	logs.Infof("📊 Grafana Dashboard preview at: /dashboards?ns=")
 	logs.Infof("🚨 Grafana Alerts preview at: /alerts?ns=")
+	logs.Infof("🔎 Resolved service endpoints at: /debug/endpoints")
+	logs.Infof("🔎 Discovered namespaces at: /discovery/namespaces")
+	if enableReloadEndpoint {
+		logs.Infof("🔄 Config reload at: POST /-/reload")
+	}
 	logs.Infof("🏥 Health endpoints: /health, /ready, /live (with /healthz, /readyz, /livez aliases)")
-	// Ensure the clients are properly closed after server starts or an error happens
-	defer func() {
-		logs.Infof("Shutting down and closing clients...")
-		for _, client := range projectClients {
-			client.Close()
-		}
-		logs.Info("All clients closed.")
-	}()
+	tlsConfig, err := web.TLSConfig()
+	if err != nil {
+		logs.Errorf("Invalid web config: %v", err)
+		states.Close()
+		logs.FlushLogAndExit(1)
+	}
 	srvCfg := server.Config{
-		EnableHTTPS: cfg.Global.EnableHTTPS,
-		HTTPPort:    cfg.Global.Port,
-		HTTPSPort:   cfg.Global.HTTPSPort,
-		CertFile:    cfg.Global.TLSCert,
-		KeyFile:     cfg.Global.TLSKey,
+		EnableHTTPS:       cfg.Global.EnableHTTPS,
+		HTTPPort:          cfg.Global.Port,
+		HTTPSPort:         cfg.Global.HTTPSPort,
+		CertFile:          cfg.Global.TLSCert,
+		KeyFile:           cfg.Global.TLSKey,
+		ReadHeaderTimeout: cfg.Global.HTTPServer.ReadHeaderTimeout(),
+		ReadTimeout:       cfg.Global.HTTPServer.ReadTimeout(),
+		WriteTimeout:      cfg.Global.HTTPServer.WriteTimeout(),
+		IdleTimeout:       cfg.Global.HTTPServer.IdleTimeout(),
+		TLS:               tlsConfig,
+	}
+	if web != nil {
+		srvCfg.DisableHTTP2 = web.HTTPServerConfig.HTTP2 != nil && !*web.HTTPServerConfig.HTTP2
+		logs.Infof("🔒 Web config %s applied (authentication per path policy)", cfg.Global.WebConfigFile)
+	}
+	// Authenticate with the web config of the current configuration, so reloads update users and tokens
+	handler := server.Authenticate(func() *server.WebConfig { return states.current().web }, mux)
+	srv := server.New(srvCfg, handler)
+	exitCode := 0
+	select {
+	case err := <-srv.Start():
+		logs.Errorf("❌ Server failed: %v", err)
+		exitCode = 1
+	case <-ctx.Done():
+		stopSignals()
+		logs.Infof("🛑 Received shutdown signal, draining")
+	}
+	shutdown(srv, states)
+	logs.FlushLogAndExit(exitCode)
+}
+
+// shutdown reports not-ready, stops accepting connections, waits for in-flight
+// scrapes up to the shutdown timeout, then stops the background loops and closes the clients.
+func shutdown(srv *server.Server, states *reloader) {
+	atomic.StoreInt32(&isDraining, 1)
+	settings := states.current().cfg.Global.HTTPServer
+	if delay := settings.DrainDelay(); delay > 0 {
+		logs.Infof("Reporting not ready for %v before closing the listeners", delay)
+		time.Sleep(delay)
 	}
-	if err := server.Start(srvCfg, nil); err != nil {
-		logs.Fatalf("Server failed: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), settings.ShutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logs.Warnf("⚠️ In-flight requests did not finish within %v: %v", settings.ShutdownTimeout(), err)
 	}
+	logs.Infof("Stopping background loops and closing clients...")
+	states.Close()
+	logs.Info("All clients closed.")
 }
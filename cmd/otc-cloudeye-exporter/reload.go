@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/clients"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/collector"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/server"
+)
+
+var (
+	reloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudeye_config_last_reload_successful",
+		Help: "Whether the last configuration reload succeeded (1) or was rejected (0).",
+	})
+	reloadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudeye_config_last_reload_success_timestamp_seconds",
+		Help: "Time of the last successful configuration load.",
+	})
+	insecureSkipVerify = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudeye_http_client_insecure_skip_verify",
+		Help: "Whether TLS certificates of cloud APIs and proxies are not verified (ignore_ssl_verify).",
+	})
+)
+
+// exporterState is everything built from clouds.yml, endpoints.yml, logs.yml and the web config file.
+// A reload builds a complete new state and swaps it in; handlers always read the
+// current one, so a scrape never mixes two configurations.
+type exporterState struct {
+	cfg          *config.Config
+	endpointCfg  *config.EndpointConfig
+	namespaces   []string
+	pool         *clients.Pool
+	nsDiscoverer *collector.NamespaceDiscoverer
+	// web is the parsed web_config_file, nil when not set
+	web    *server.WebConfig
+	cancel context.CancelFunc
+}
+
+// logsConfPath returns the logs.yml path of a config.
+func logsConfPath(cfg *config.Config) string {
+	if cfg != nil && cfg.Global.LogsConfPath != "" {
+		return cfg.Global.LogsConfPath
+	}
+	return constants.DefaultLogsConfPath
+}
+
+// loadLogger builds the logger of a config; logs.yml is optional unless its path is set explicitly.
+func loadLogger(cfg *config.Config) (*zap.SugaredLogger, error) {
+	logger, err := logs.LoadLog(logsConfPath(cfg), cfg.Global.LogsConfPath != "")
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", logsConfPath(cfg), err)
+	}
+	return logger, nil
+}
+
+// buildState loads and validates the configuration and creates its clients.
+// Nothing is started or published, so a failed build leaves the running state untouched.
+func buildState(configPath string, overrides *config.Overrides) (*exporterState, error) {
+	cfg, endpointCfg, err := loadConfigs(configPath, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	namespaces, err := parseNamespaces(cfg.Global.Namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespaces %q: %w", cfg.Global.Namespaces, err)
+	}
+	web, err := server.LoadWebConfig(cfg.Global.WebConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading web config: %w", err)
+	}
+	// Log endpoint configuration for each namespace
+	warnMissingEndpoints(cfg, endpointCfg, namespaces)
+	projectClients, err := clients.NewClientsWithEndpoints(cfg, endpointCfg)
+	if err != nil {
+		return nil, &config.ResolveError{Err: fmt.Errorf("initializing OTC clients: %w", err)}
+	}
+	logs.Infof("OTC clients initialized successfully for %d projects", len(projectClients))
+	state := &exporterState{
+		cfg:         cfg,
+		endpointCfg: endpointCfg,
+		namespaces:  namespaces,
+		pool:        clients.NewPool(projectClients),
+		web:         web,
+	}
+	// Collect every namespace CES has metrics in, per project
+	if cfg.Global.NamespaceDiscovery.Enabled {
+		state.nsDiscoverer = collector.NewNamespaceDiscoverer(cfg, state.pool)
+	}
+	return state, nil
+}
+
+// start launches the background loops of the state.
+func (s *exporterState) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	// Periodically pick up new projects and drop deleted ones
+	if s.cfg.Global.ProjectDiscovery.Enabled {
+		go clients.NewProjectDiscoverer(s.cfg, s.endpointCfg, s.pool).Run(ctx)
+	}
+	if s.nsDiscoverer != nil {
+		go s.nsDiscoverer.Run(ctx)
+	}
+}
+
+// stop ends the background loops and closes the clients of the state.
+func (s *exporterState) stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.pool.Close()
+	s.cfg.Global.CloseIdleConnections()
+}
+
+// reloader owns the current state and replaces it when the configuration changes.
+type reloader struct {
+	configPath string
+	overrides  *config.Overrides
+	state      atomic.Pointer[exporterState]
+
+	// mu serializes reloads
+	mu sync.Mutex
+	// fingerprint is the content hash of the files of the current state
+	fingerprint string
+	// closed is set on shutdown; later reloads are refused
+	closed bool
+}
+
+// newReloader builds and starts the initial state; an invalid configuration is fatal here.
+func newReloader(configPath string, overrides *config.Overrides) (*reloader, error) {
+	r := &reloader{configPath: configPath, overrides: overrides}
+	state, err := buildState(configPath, overrides)
+	if err != nil {
+		return nil, err
+	}
+	// logs.yml was read from the default path before the config was known
+	if logsConfPath(state.cfg) != constants.DefaultLogsConfPath {
+		logger, err := loadLogger(state.cfg)
+		if err != nil {
+			state.pool.Close()
+			return nil, err
+		}
+		logs.SetLog(logger)
+	}
+	r.fingerprint = r.configFingerprint(state.cfg)
+	r.activate(state)
+	return r, nil
+}
+
+// current returns the active state.
+func (r *reloader) current() *exporterState {
+	return r.state.Load()
+}
+
+func (r *reloader) activate(state *exporterState) {
+	config.Publish(state.cfg)
+	state.start()
+	r.state.Store(state)
+	reloadSuccess.Set(1)
+	reloadTimestamp.SetToCurrentTime()
+	if state.cfg.Global.IgnoreSSLVerify {
+		insecureSkipVerify.Set(1)
+	} else {
+		insecureSkipVerify.Set(0)
+	}
+}
+
+// Close stops the background loops and closes the clients of the current state.
+// It waits for a running reload to finish.
+func (r *reloader) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.closed = true
+		r.current().stop()
+	}
+}
+
+// Reload validates the configuration files and switches to them. If anything is
+// invalid, the error is returned and the current configuration keeps running.
+func (r *reloader) Reload(trigger string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("exporter is shutting down")
+	}
+	logs.Infof("🔄 Reloading configuration (%s)", trigger)
+	err := r.reload()
+	if err != nil {
+		// Remember invalid files so the watcher does not retry them until they change
+		// again; after a secret store, IAM or network failure it retries on the next tick
+		var resolveErr *config.ResolveError
+		if !errors.As(err, &resolveErr) {
+			r.fingerprint = r.configFingerprint(r.current().cfg)
+		}
+		reloadSuccess.Set(0)
+		logs.Errorf("❌ Configuration reload rejected, keeping the current configuration: %v", err)
+		return err
+	}
+	logs.Infof("✅ Configuration reloaded (%s)", trigger)
+	return nil
+}
+
+func (r *reloader) reload() error {
+	old := r.current()
+	state, err := buildState(r.configPath, r.overrides)
+	if err != nil {
+		return err
+	}
+	// Unlike at startup, a reload does not drop accounts: their series would vanish
+	// until the next reload, e.g. after a short IAM or secret store outage
+	if err := state.cfg.SkippedAccounts(); err != nil {
+		state.pool.Close()
+		state.cfg.Global.CloseIdleConnections()
+		return &config.ResolveError{Err: fmt.Errorf("not every account could be resolved:\n%w", err)}
+	}
+	logger, err := loadLogger(state.cfg)
+	if err != nil {
+		state.pool.Close()
+		return err
+	}
+	warnRestartRequired(old, state)
+	logs.SetLog(logger)
+	r.fingerprint = r.configFingerprint(state.cfg)
+	r.activate(state)
+	old.stop()
+	return nil
+}
+
+// warnRestartRequired logs settings that only take effect after a restart.
+func warnRestartRequired(old, updated *exporterState) {
+	g, n := old.cfg.Global, updated.cfg.Global
+	if g.Port != n.Port || g.EnableHTTPS != n.EnableHTTPS || g.HTTPSPort != n.HTTPSPort ||
+		g.TLSCert != n.TLSCert || g.TLSKey != n.TLSKey || g.MetricPath != n.MetricPath {
+		logs.Warnf("⚠️ Server settings (port, enable_https, https_port, tls_cert, tls_key, metric_path) changed; they take effect after a restart")
+	}
+	gs, ns := g.HTTPServer, n.HTTPServer
+	if gs.ReadHeaderTimeout() != ns.ReadHeaderTimeout() || gs.ReadTimeout() != ns.ReadTimeout() ||
+		gs.WriteTimeout() != ns.WriteTimeout() || gs.IdleTimeout() != ns.IdleTimeout() {
+		logs.Warnf("⚠️ http_server timeouts changed; they take effect after a restart")
+	}
+	// Users, tokens, path policies and headers of the web config are reloaded; TLS is not
+	var oldTLS, newTLS *server.TLSServerConfig
+	var oldHTTP2, newHTTP2 *bool
+	if old.web != nil {
+		oldTLS, oldHTTP2 = old.web.TLSServerConfig, old.web.HTTPServerConfig.HTTP2
+	}
+	if updated.web != nil {
+		newTLS, newHTTP2 = updated.web.TLSServerConfig, updated.web.HTTPServerConfig.HTTP2
+	}
+	if !reflect.DeepEqual(oldTLS, newTLS) || !reflect.DeepEqual(oldHTTP2, newHTTP2) {
+		logs.Warnf("⚠️ Web config TLS settings (tls_server_config, http2) changed; they take effect after a restart")
+	}
+}
+
+// configFingerprint hashes the content of clouds.yml (or the config directory), endpoints.yml,
+// logs.yml and the web config file.
+// Contents are compared rather than modification times, so atomically replaced
+// files (e.g. Kubernetes ConfigMap updates) are detected as well.
+func (r *reloader) configFingerprint(cfg *config.Config) string {
+	endpointsPath := cfg.Global.EndpointsConfPath
+	if endpointsPath == "" {
+		endpointsPath = constants.DefaultEndpointsConfPath
+	}
+	// A config directory contributes all of its fragments
+	files, err := config.SourceFiles(r.configPath)
+	if err != nil {
+		files = []string{r.configPath}
+	}
+	h := sha256.New()
+	files = append(files, endpointsPath, logsConfPath(cfg))
+	if cfg.Global.WebConfigFile != "" {
+		files = append(files, cfg.Global.WebConfigFile)
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// A missing optional file is part of the fingerprint too
+			data = []byte(err.Error())
+		}
+		h.Write([]byte(path))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// watchSignals reloads on SIGHUP.
+func (r *reloader) watchSignals(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.Reload("SIGHUP")
+		}
+	}
+}
+
+// watchFiles reloads when the content of a configuration file changes.
+func (r *reloader) watchFiles(ctx context.Context, interval time.Duration) {
+	logs.Infof("🔄 Watching configuration files for changes every %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			changed := r.configFingerprint(r.current().cfg) != r.fingerprint
+			r.mu.Unlock()
+			if changed {
+				r.Reload("file change")
+			}
+		}
+	}
+}
+
+// reloadHandler handles POST /-/reload.
+func reloadHandler(r *reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost && req.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, "Only POST or PUT requests allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.Reload("admin endpoint"); err != nil {
+			http.Error(w, fmt.Sprintf("failed to reload config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
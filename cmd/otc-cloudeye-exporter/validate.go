@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/server"
+)
+
+// runValidate implements "otc-cloudeye-exporter validate --config clouds.yml".
+// It checks clouds.yml, endpoints.yml and logs.yml offline: no secrets are
+// resolved and no cloud API is called. It returns the process exit code.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	configPath := flags.String("config", "clouds.yml", "Path to the config YAML file or a directory of fragments (\"\" to use flags and environment variables only)")
+	overrides := config.RegisterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	path := configFileOrNone(flags, *configPath)
+	cfg, err := config.ParseConfig(path, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	endpointsPath := cfg.Global.EndpointsConfPath
+	required := endpointsPath != ""
+	if !required {
+		endpointsPath = constants.DefaultEndpointsConfPath
+	}
+	if _, err := config.LoadEndpointConfig(endpointsPath, required); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ invalid endpoint config %s: %v\n", endpointsPath, err)
+		return 1
+	}
+	if _, err := loadLogger(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ invalid logging config: %v\n", err)
+		return 1
+	}
+	if _, err := server.LoadWebConfig(cfg.Global.WebConfigFile); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ invalid web config: %v\n", err)
+		return 1
+	}
+	if path == "" {
+		path = "Configuration"
+	}
+	if cfg.Global.IgnoreSSLVerify {
+		fmt.Fprintln(os.Stderr, "⚠️ ignore_ssl_verify is enabled: TLS certificates are not verified; set http_client.tls.ca_bundle instead")
+	}
+	fmt.Printf("✅ %s is valid (%d accounts, namespaces %q)\n", path, cfg.AccountCount(), cfg.Global.Namespaces)
+	return 0
+}
+
+// printEffectiveConfig prints the merged config with overrides and defaults applied
+// and secrets redacted. It returns the process exit code.
+func printEffectiveConfig(path string, overrides *config.Overrides) int {
+	cfg, err := config.ParseConfig(path, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	out, err := cfg.EffectiveYAML()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ rendering effective config: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(out)
+	return 0
+}
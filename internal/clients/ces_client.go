@@ -5,7 +5,6 @@ import (
 
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
-	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
 	ces "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1"
 	cesv2 "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v2"
 )
@@ -14,16 +13,12 @@ import (
 func InitCESClient(cfg *config.Config, endpoint string, projectID string) (*ces.CesClient, error) {
 	logs.Infof("Initializing CES v1 client for project: %s, endpoint: %s", projectID, endpoint)
 
-	auth, _ := basic.NewCredentialsBuilder().
-		WithAk(cfg.Auth.AccessKey).
-		WithSk(cfg.Auth.SecretKey).
-		WithProjectId(projectID).
-		SafeBuild()
+	creds := cfg.Auth.Credentials()
 
 	hcClient, err := ces.CesClientBuilder().
 		WithEndpoints([]string{endpoint}).
-		WithCredential(auth).
-		WithHttpConfig(config.GetHttpConfig().WithIgnoreSSLVerification(cfg.Global.IgnoreSSLVerify)).
+		WithCredential(creds.BasicSnapshot(projectID)).
+		WithHttpConfig(cfg.Global.HttpConfig()).
 		SafeBuild()
 	if err != nil {
 		logs.Errorf("Failed to build CES v1 client for project %s: %v", projectID, err)
@@ -32,23 +27,20 @@ func InitCESClient(cfg *config.Config, endpoint string, projectID string) (*ces.
 
 	logs.Infof("CES v1 client successfully initialized for project: %s", projectID)
 
-	return ces.NewCesClient(hcClient), nil
+	// Sign with the account's shared (possibly renewed) credentials
+	return ces.NewCesClient(hcClient.WithCredential(creds.Basic(projectID))), nil
 }
 
 // InitCESv2Client initializes CES v2 client with SafeBuild
 func InitCESv2Client(cfg *config.Config, endpoint string, projectID string) (*cesv2.CesClient, error) {
 	logs.Infof("Initializing CES v2 client for project: %s, endpoint: %s", projectID, endpoint)
 
-	auth, _ := basic.NewCredentialsBuilder().
-		WithAk(cfg.Auth.AccessKey).
-		WithSk(cfg.Auth.SecretKey).
-		WithProjectId(projectID).
-		SafeBuild()
+	creds := cfg.Auth.Credentials()
 
 	hcClient, err := cesv2.CesClientBuilder().
 		WithEndpoints([]string{endpoint}).
-		WithCredential(auth).
-		WithHttpConfig(config.GetHttpConfig().WithIgnoreSSLVerification(cfg.Global.IgnoreSSLVerify)).
+		WithCredential(creds.BasicSnapshot(projectID)).
+		WithHttpConfig(cfg.Global.HttpConfig()).
 		SafeBuild()
 	if err != nil {
 		logs.Errorf("Failed to build CES v2 client for project %s: %v", projectID, err)
@@ -57,5 +49,5 @@ func InitCESv2Client(cfg *config.Config, endpoint string, projectID string) (*ce
 
 	logs.Infof("CES v2 client successfully initialized for project: %s", projectID)
 
-	return cesv2.NewCesClient(hcClient), nil
+	return cesv2.NewCesClient(hcClient.WithCredential(creds.Basic(projectID))), nil
 }
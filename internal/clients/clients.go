@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
@@ -17,6 +18,9 @@ type Clients struct {
 	RMS         *RmsClient
 	EVS         *evs.EvsClient
 	OBS         *ObsClient
+	Account     string
+	DomainName  string
+	Region      string
 	ProjectName string
 	ProjectID   string
 }
@@ -25,79 +29,121 @@ var (
 	cacheCleaner sync.Once
 )
 
-// NewClientsWithEndpoints creates all service clients using static OTC endpoints
+// NewClientsWithEndpoints creates all service clients for every configured account, region and project
 func NewClientsWithEndpoints(cfg *config.Config, epCfg *config.EndpointConfig) ([]*Clients, error) {
 	var clientsList []*Clients
-	region := epCfg.Region
+	for _, account := range cfg.Accounts {
+		acctCfg := cfg.ForAccount(account)
+		for _, region := range account.Regions {
+			regionClients, err := newRegionClients(acctCfg, account.Name, region, epCfg.ForRegion(region.Name, region.Endpoints))
+			if err != nil {
+				logs.Errorf("❌ Skipping region %s of account %s: %v", region.Name, account.Name, err)
+				continue
+			}
+			clientsList = append(clientsList, regionClients...)
+		}
+	}
+
+	if len(clientsList) == 0 {
+		logs.Errorf("No CES clients initialized successfully")
+		logs.Flush()
+		return nil, fmt.Errorf("no CES clients initialized successfully")
+	}
+	logs.Info("Successfully initialized clients")
+	logs.Flush()
+	return clientsList, nil
+}
+
+// newRegionClients creates the service clients for all projects of one account region.
+// cfg.Auth must hold the account's credentials.
+func newRegionClients(cfg *config.Config, account string, region config.RegionConfig, epCfg *config.EndpointConfig) ([]*Clients, error) {
+	var clientsList []*Clients
+	logs.Info("Initializing clients for region: ", region.Name)
+	for _, project := range region.Projects {
+		client, err := NewProjectClients(cfg, account, region.Name, project, epCfg)
+		if err != nil {
+			if errors.Is(err, errEndpointMissing) {
+				return nil, err
+			}
+			continue
+		}
+		clientsList = append(clientsList, client)
+	}
+	return clientsList, nil
+}
+
+var errEndpointMissing = errors.New("endpoint missing")
 
-	// Strictly require each endpoint to be in YAML!
+// NewProjectClients creates all service clients for a single project.
+// cfg.Auth must hold the account's credentials and epCfg the region's resolved endpoints.
+func NewProjectClients(cfg *config.Config, account, region string, project config.ProjectConfig, epCfg *config.EndpointConfig) (*Clients, error) {
+	// Each endpoint comes from the built-in catalog unless overridden
 	cesEndpoint, ok := epCfg.Services["SYS.CES"]
 	if !ok {
-		logs.Errorf("SYS.CES endpoint not defined in endpoints.yml!")
-		return nil, fmt.Errorf("SYS.CES endpoint missing")
+		logs.Errorf("SYS.CES endpoint not defined for region %s!", region)
+		return nil, fmt.Errorf("SYS.CES %w", errEndpointMissing)
 	}
 	rmsEndpoint, ok := epCfg.Services["SYS.RMS"]
 	if !ok {
-		logs.Errorf("SYS.RMS endpoint not defined in endpoints.yml!")
-		return nil, fmt.Errorf("SYS.RMS endpoint missing")
+		logs.Errorf("SYS.RMS endpoint not defined for region %s!", region)
+		return nil, fmt.Errorf("SYS.RMS %w", errEndpointMissing)
 	}
 	evsEndpoint, ok := epCfg.Services["SYS.EVS"]
 	if !ok {
-		logs.Errorf("SYS.EVS endpoint not defined in endpoints.yml!")
-		return nil, fmt.Errorf("SYS.EVS endpoint missing")
+		logs.Errorf("SYS.EVS endpoint not defined for region %s!", region)
+		return nil, fmt.Errorf("SYS.EVS %w", errEndpointMissing)
 	}
 	obsEndpoint, ok := epCfg.Services["SYS.OBS"]
 	if !ok {
-		logs.Errorf("SYS.OBS endpoint not defined in endpoints.yml!")
-		return nil, fmt.Errorf("SYS.OBS endpoint missing")
+		logs.Errorf("SYS.OBS endpoint not defined for region %s!", region)
+		return nil, fmt.Errorf("SYS.OBS %w", errEndpointMissing)
 	}
-
-	logs.Info("Initializing clients for region: ", region)
-	for _, project := range cfg.Auth.Projects {
-		logs.Info("Initializing clients for project: ", project.Name)
-		v1Client, err := InitCESClient(cfg, cesEndpoint, project.ID)
-		if err != nil {
-			logs.Errorf("❌ Failed to init CES v1 for project %s: %v", project.Name, err)
-			continue
-		}
-		v2Client, err := InitCESv2Client(cfg, cesEndpoint, project.ID)
-		if err != nil {
-			logs.Errorf("❌ Failed to init CES v2 for project %s: %v", project.Name, err)
-			continue
-		}
-		rmsClient, err := InitRmsClient(cfg, rmsEndpoint, region)
-		if err != nil {
-			logs.Errorf("❌ Failed to init RMS client for project %s: %v", project.Name, err)
-			continue
-		}
-		evsClient, err := InitEVSClient(cfg, evsEndpoint, project.ID)
-		if err != nil {
-			logs.Errorf("❌ Failed init EVS client for project %s: %v", project.Name, err)
-		}
-		obsClient, err := InitObsClient(cfg, obsEndpoint)
-		if err != nil {
-			logs.Errorf("❌ Failed to init OBS client for project %s: %v", project.Name, err)
-		}
-		client := &Clients{
-			CloudEyeV1:  v1Client,
-			CloudEyeV2:  v2Client,
-			RMS:         rmsClient,
-			EVS:         evsClient,
-			OBS:         obsClient,
-			ProjectName: project.Name,
-			ProjectID:   project.ID,
-		}
-		clientsList = append(clientsList, client)
+	if project.ID == "" {
+		logs.Errorf("❌ Project %s has no ID, skipping", project.Name)
+		return nil, fmt.Errorf("project %s has no ID", project.Name)
 	}
 
-	if len(clientsList) == 0 {
-		logs.Errorf("No CES clients initialized successfully")
-		logs.Flush()
-		return nil, fmt.Errorf("no CES clients initialized successfully")
+	logs.Info("Initializing clients for project: ", project.Name)
+	v1Client, err := InitCESClient(cfg, cesEndpoint, project.ID)
+	if err != nil {
+		logs.Errorf("❌ Failed to init CES v1 for project %s: %v", project.Name, err)
+		return nil, err
 	}
-	logs.Info("Successfully initialized clients")
-	logs.Flush()
-	return clientsList, nil
+	v2Client, err := InitCESv2Client(cfg, cesEndpoint, project.ID)
+	if err != nil {
+		logs.Errorf("❌ Failed to init CES v2 for project %s: %v", project.Name, err)
+		return nil, err
+	}
+	rmsClient, err := InitRmsClient(cfg, rmsEndpoint, region)
+	if err != nil {
+		logs.Errorf("❌ Failed to init RMS client for project %s: %v", project.Name, err)
+		return nil, err
+	}
+	evsClient, err := InitEVSClient(cfg, evsEndpoint, project.ID)
+	if err != nil {
+		logs.Errorf("❌ Failed init EVS client for project %s: %v", project.Name, err)
+	}
+	obsClient, err := InitObsClient(cfg, obsEndpoint)
+	if err != nil {
+		logs.Errorf("❌ Failed to init OBS client for project %s: %v", project.Name, err)
+	}
+	return &Clients{
+		CloudEyeV1:  v1Client,
+		CloudEyeV2:  v2Client,
+		RMS:         rmsClient,
+		EVS:         evsClient,
+		OBS:         obsClient,
+		Account:     account,
+		DomainName:  cfg.Auth.DomainName,
+		Region:      region,
+		ProjectName: project.Name,
+		ProjectID:   project.ID,
+	}, nil
+}
+
+// Key identifies the clients of a project across accounts and regions
+func (c *Clients) Key() string {
+	return c.Account + "/" + c.Region + "/" + c.ProjectID
 }
 
 // Close releases resources associated with the Clients struct
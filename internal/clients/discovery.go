@@ -0,0 +1,84 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+)
+
+// ProjectDiscoverer periodically lists IAM projects and hot-adds or removes
+// the per-project clients in the pool.
+type ProjectDiscoverer struct {
+	cfg      *config.Config
+	epCfg    *config.EndpointConfig
+	pool     *Pool
+	interval time.Duration
+}
+
+// NewProjectDiscoverer creates a discoverer for all configured accounts and regions.
+func NewProjectDiscoverer(cfg *config.Config, epCfg *config.EndpointConfig, pool *Pool) *ProjectDiscoverer {
+	interval := time.Duration(cfg.Global.ProjectDiscovery.RefreshIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = constants.DefaultProjectRefreshInterval
+	}
+	return &ProjectDiscoverer{cfg: cfg, epCfg: epCfg, pool: pool, interval: interval}
+}
+
+// Run refreshes the project set every interval until ctx is cancelled.
+func (d *ProjectDiscoverer) Run(ctx context.Context) {
+	logs.Infof("🔄 Project discovery enabled, refreshing every %v", d.interval)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logs.Info("Project discovery stopped")
+			return
+		case <-ticker.C:
+			d.Refresh()
+		}
+	}
+}
+
+// Refresh lists the current projects of every account region and syncs the pool.
+// Regions whose listing fails keep their current projects.
+func (d *ProjectDiscoverer) Refresh() {
+	filter := &d.cfg.Global.ProjectDiscovery.PatternFilter
+	for _, account := range d.cfg.Accounts {
+		acctCfg := d.cfg.ForAccount(account)
+		for _, region := range account.Regions {
+			projects, err := config.DiscoverProjects(account.CloudAuth, region, filter)
+			if err != nil {
+				logs.Warnf("Project discovery failed for account %s region %s: %v", account.Name, region.Name, err)
+				continue
+			}
+			d.syncRegion(acctCfg, account.Name, region, projects)
+		}
+	}
+}
+
+func (d *ProjectDiscoverer) syncRegion(acctCfg *config.Config, account string, region config.RegionConfig, projects []config.ProjectConfig) {
+	desired := make(map[string]bool, len(projects))
+	regionEndpoints := d.epCfg.ForRegion(region.Name, region.Endpoints)
+	for _, project := range projects {
+		key := account + "/" + region.Name + "/" + project.ID
+		desired[key] = true
+		if d.pool.Has(key) {
+			continue
+		}
+		client, err := NewProjectClients(acctCfg, account, region.Name, project, regionEndpoints)
+		if err != nil {
+			logs.Errorf("❌ Failed to add discovered project %s: %v", project.Name, err)
+			continue
+		}
+		d.pool.Add(client)
+	}
+	for _, client := range d.pool.Clients() {
+		if client.Account == account && client.Region == region.Name && !desired[client.Key()] {
+			d.pool.Remove(client.Key())
+		}
+	}
+}
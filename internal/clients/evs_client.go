@@ -5,7 +5,6 @@ import (
 
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
-	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
 	evs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2"
 	evsModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
 )
@@ -13,22 +12,18 @@ import (
 // InitEVSClient initializes the EVS client for a specific project
 func InitEVSClient(cfg *config.Config, endpoint string, projectID string) (*evs.EvsClient, error) {
 	logs.Infof("Initializing EVS client for endpoint: %s, project: %s", endpoint, projectID)
-	auth, _ := basic.NewCredentialsBuilder().
-		WithAk(cfg.Auth.AccessKey).
-		WithSk(cfg.Auth.SecretKey).
-		WithProjectId(projectID).
-		SafeBuild()
+	creds := cfg.Auth.Credentials()
 	hcClient, err := evs.EvsClientBuilder().
 		WithEndpoints([]string{endpoint}).
-		WithCredential(auth).
-		WithHttpConfig(config.GetHttpConfig().WithIgnoreSSLVerification(cfg.Global.IgnoreSSLVerify)).
+		WithCredential(creds.BasicSnapshot(projectID)).
+		WithHttpConfig(cfg.Global.HttpConfig()).
 		SafeBuild()
 	if err != nil {
 		logs.Errorf("Failed to build EVS client: %v", err)
 		return nil, fmt.Errorf("failed to build EVS client: %w", err)
 	}
 	logs.Infof("Successfully initialized EVS client for project: %s", projectID)
-	return evs.NewEvsClient(hcClient), nil
+	return evs.NewEvsClient(hcClient.WithCredential(creds.Basic(projectID))), nil
 }
 
 // ListVolumes lists EVS volumes for the attached EVS client
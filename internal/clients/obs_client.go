@@ -3,6 +3,7 @@ package clients
 import (
 	"fmt"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/credentials"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
 	obs "github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
 	"sync"
@@ -76,19 +77,27 @@ func startObsCacheCleaner() {
 
 type ObsClient struct {
 	client *obs.ObsClient
+	// refresher pushes renewed account credentials into the client
+	refresher *credentials.ObsRefresher
 }
 
 // InitObsClient initializes an OBS client
 func InitObsClient(cfg *config.Config, endpoint string) (*ObsClient, error) {
 	logs.Infof("Initializing OBS client for endpoint: %s", endpoint)
-	obsClient, err := obs.New(cfg.Auth.AccessKey, cfg.Auth.SecretKey, endpoint)
+	src := cfg.Auth.Credentials()
+	refresher := src.NewObsRefresher()
+	creds := src.Get()
+	obsClient, err := obs.New(creds.AccessKey, creds.SecretKey, endpoint,
+		obs.WithSecurityToken(creds.SecurityToken),
+		// Same proxy, timeouts and TLS settings as the SDK clients
+		obs.WithHttpClient(cfg.Global.NewHTTPClient()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OBS client: %w", err)
 	}
 	// Start cache cleaner only once
 	cacheCleaner.Do(startObsCacheCleaner)
 	logs.Infof("OBS client initialized for endpoint: %s", endpoint)
-	return &ObsClient{client: obsClient}, nil
+	return &ObsClient{client: obsClient, refresher: refresher}, nil
 }
 
 // GetBucketTags fetches and caches bucket tags
@@ -103,6 +112,7 @@ func (o *ObsClient) GetBucketTags(bucketName string) (map[string]string, error)
 		return data, nil
 	}
 	logs.Debugf("OBS bucket tag cache miss for %s, querying API", bucketName)
+	o.refresher.Refresh(o.client)
 	output, err := o.client.GetBucketTagging(bucketName)
 	if err != nil {
 		// No tags is normal
@@ -138,6 +148,7 @@ func (o *ObsClient) GetBucketInfo(bucketName string) (map[string]string, error)
 		return data, nil
 	}
 	logs.Debugf("OBS bucket info cache miss for %s, querying API", bucketName)
+	o.refresher.Refresh(o.client)
 	locationOutput, err := o.client.GetBucketLocation(bucketName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bucket location for %s: %w", bucketName, err)
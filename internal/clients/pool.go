@@ -0,0 +1,102 @@
+package clients
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var projectsDesc = prometheus.NewDesc(
+	"cloudeye_projects",
+	"Projects currently collected by the exporter (always 1).",
+	[]string{"account", "region", "project", "project_id"},
+	nil,
+)
+
+// Pool holds the per-project clients and allows hot-adding and removing projects.
+// It also exports the current project set as the cloudeye_projects metric.
+type Pool struct {
+	mu      sync.RWMutex
+	clients map[string]*Clients
+}
+
+// NewPool creates a pool with the given initial clients.
+func NewPool(initial []*Clients) *Pool {
+	p := &Pool{clients: make(map[string]*Clients, len(initial))}
+	for _, c := range initial {
+		p.clients[c.Key()] = c
+	}
+	return p
+}
+
+// Clients returns a snapshot of all clients, sorted by key.
+func (p *Pool) Clients() []*Clients {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	list := make([]*Clients, 0, len(p.clients))
+	for _, c := range p.clients {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key() < list[j].Key() })
+	return list
+}
+
+// Len returns the number of projects in the pool.
+func (p *Pool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.clients)
+}
+
+// Has reports whether a project is in the pool.
+func (p *Pool) Has(key string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.clients[key]
+	return ok
+}
+
+// Add inserts (or replaces) the clients of a project.
+func (p *Pool) Add(c *Clients) {
+	p.mu.Lock()
+	old := p.clients[c.Key()]
+	p.clients[c.Key()] = c
+	p.mu.Unlock()
+	if old != nil && old != c {
+		old.Close()
+	}
+	logs.Infof("➕ Added project %s (%s) in region %s for account %s", c.ProjectName, c.ProjectID, c.Region, c.Account)
+}
+
+// Remove drops the clients of a project and closes them.
+func (p *Pool) Remove(key string) {
+	p.mu.Lock()
+	c, ok := p.clients[key]
+	delete(p.clients, key)
+	p.mu.Unlock()
+	if ok {
+		logs.Infof("➖ Removed project %s (%s) in region %s for account %s", c.ProjectName, c.ProjectID, c.Region, c.Account)
+		c.Close()
+	}
+}
+
+// Close closes all clients in the pool.
+func (p *Pool) Close() {
+	for _, c := range p.Clients() {
+		c.Close()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (p *Pool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- projectsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (p *Pool) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range p.Clients() {
+		ch <- prometheus.MustNewConstMetric(projectsDesc, prometheus.GaugeValue, 1, c.Account, c.Region, c.ProjectName, c.ProjectID)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
-	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
 	rms "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/rms/v1"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/rms/v1/model"
 	"reflect"
@@ -76,29 +75,26 @@ func startRmsCacheCleaner() {
 
 type RmsClient struct {
 	client *rms.RmsClient
+
+	inventoryMu   sync.Mutex
+	inventory     map[string]map[string]string
+	inventoryTime time.Time
 }
 
 func InitRmsClient(cfg *config.Config, endpoint, region string) (*RmsClient, error) {
 	logs.Infof("Initializing RMS client for region: %s, endpoint: %s", region, endpoint)
-	auth, err := global.NewCredentialsBuilder().
-		WithAk(cfg.Auth.AccessKey).
-		WithSk(cfg.Auth.SecretKey).
-		WithDomainId(cfg.Auth.DomainID).
-		SafeBuild()
-	if err != nil {
-		return nil, fmt.Errorf("failed to build credentials: %w", err)
-	}
+	creds := cfg.Auth.Credentials()
 	hcClient, err := rms.RmsClientBuilder().
 		WithEndpoints([]string{endpoint}).
-		WithCredential(auth).
-		WithHttpConfig(config.GetHttpConfig().WithIgnoreSSLVerification(cfg.Global.IgnoreSSLVerify)).
+		WithCredential(creds.GlobalSnapshot(cfg.Auth.DomainID)).
+		WithHttpConfig(cfg.Global.HttpConfig()).
 		SafeBuild()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build RMS client: %w", err)
 	}
 	cacheCleaner.Do(startRmsCacheCleaner)
 	logs.Infof("RMS client initialized for region: %s", region)
-	return &RmsClient{client: rms.NewRmsClient(hcClient)}, nil
+	return &RmsClient{client: rms.NewRmsClient(hcClient.WithCredential(creds.Global(cfg.Auth.DomainID)))}, nil
 }
 
 // GetResourceByID fetches resource metadata, using cache when possible.
@@ -143,6 +139,7 @@ func (r *RmsClient) lookupResource(resourceID, resourceName string) (map[string]
 			if matchesResource(&res, resourceID, resourceName) {
 				info := structToStringMap(res)
 				mergeTags(info, res.Tags)
+				mergeProperties(info, res.Properties)
 				return info, nil
 			}
 		}
@@ -217,6 +214,23 @@ func mergeTags(info map[string]string, tags map[string]string) {
 	}
 }
 
+// mergeProperties flattens scalar resource properties into the info map with a "prop_" prefix.
+// Nested objects (e.g. ECS metadata) are flattened one level deep as "prop_<key>.<subkey>".
+func mergeProperties(info map[string]string, props map[string]interface{}) {
+	for k, v := range props {
+		switch val := v.(type) {
+		case string:
+			info["prop_"+k] = val
+		case map[string]interface{}:
+			for sk, sv := range val {
+				if str, ok := sv.(string); ok {
+					info["prop_"+k+"."+sk] = str
+				}
+			}
+		}
+	}
+}
+
 // ListAllResources fetches all resources from RMS.
 func (r *RmsClient) ListAllResources() ([]map[string]string, error) {
 	var results []map[string]string
@@ -231,6 +245,7 @@ func (r *RmsClient) ListAllResources() ([]map[string]string, error) {
 			for _, res := range *resp.Resources {
 				info := structToStringMap(res)
 				mergeTags(info, res.Tags)
+				mergeProperties(info, res.Properties)
 				results = append(results, info)
 			}
 		}
@@ -241,3 +256,30 @@ func (r *RmsClient) ListAllResources() ([]map[string]string, error) {
 	}
 	return results, nil
 }
+
+// GetInventory returns all RMS resources keyed by resource ID, cached for rmsCacheTTL.
+// Fetched resources are also stored in the per-resource cache used for enrichment.
+func (r *RmsClient) GetInventory() (map[string]map[string]string, error) {
+	r.inventoryMu.Lock()
+	defer r.inventoryMu.Unlock()
+	if r.inventory != nil && time.Since(r.inventoryTime) < rmsCacheTTL {
+		return r.inventory, nil
+	}
+	resources, err := r.ListAllResources()
+	if err != nil {
+		return nil, err
+	}
+	inventory := make(map[string]map[string]string, len(resources))
+	for _, info := range resources {
+		id := info["id"]
+		if id == "" {
+			continue
+		}
+		inventory[id] = info
+		cacheResource(info, "id:"+id)
+	}
+	r.inventory = inventory
+	r.inventoryTime = time.Now()
+	logs.Infof("Refreshed RMS inventory with %d resources", len(inventory))
+	return inventory, nil
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/registry"
+)
+
+// applyCCEEnrichment adds cce_cluster/cce_nodepool labels when the RMS resource
+// carries CCE node tags or metadata, so VM metrics can be joined with kube-state-metrics.
+func applyCCEEnrichment(labels map[string]string, rmsResource map[string]string, namespace string, cfg *config.Config) map[string]string {
+	cce := cfg.Global.CCELabels
+	if !cce.Enabled || !isCCENamespace(namespace, cce) {
+		return labels
+	}
+	if cluster := lookupCCEValue(rmsResource, cce.ClusterKeys, constants.DefaultCCEClusterKeys); cluster != "" {
+		labels[constants.LabelCCECluster] = cluster
+	}
+	if nodePool := lookupCCEValue(rmsResource, cce.NodePoolKeys, constants.DefaultCCENodePoolKeys); nodePool != "" {
+		labels[constants.LabelCCENodePool] = nodePool
+	}
+	if _, ok := labels[constants.LabelCCECluster]; ok {
+		logs.Debugf("Resource %s recognized as CCE node of cluster %s", rmsResource["id"], labels[constants.LabelCCECluster])
+	}
+	return labels
+}
+
+// isCCENamespace uses the configured namespaces, or the registry's default CCE enricher.
+func isCCENamespace(namespace string, cce config.CCELabelsConfig) bool {
+	if len(cce.Namespaces) == 0 {
+		return registry.HasEnricher(namespace, registry.EnricherCCE)
+	}
+	for _, ns := range cce.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupCCEValue returns the first non-empty value found for the given keys,
+// checking RMS tags before resource properties.
+func lookupCCEValue(rmsResource map[string]string, keys, defaultKeys []string) string {
+	if len(keys) == 0 {
+		keys = defaultKeys
+	}
+	for _, key := range keys {
+		if v := rmsResource["tag_"+key]; v != "" {
+			return v
+		}
+		if v := rmsResource["prop_"+key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
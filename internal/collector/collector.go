@@ -8,6 +8,7 @@ import (
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/registry"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -18,10 +19,10 @@ type CloudEyeCollector struct {
 }
 
 func NewCloudEyeCollector(cfg *config.Config, services []string) *CloudEyeCollector {
-	// Validate services against supported namespaces
+	// Built-in namespaces are accepted as-is; others are validated against CES on collect
 	validServices := make([]string, 0, len(services))
 	for _, service := range services {
-		if isValidNamespace(service) {
+		if registry.IsBuiltin(service) || config.IsNamespaceName(service) {
 			validServices = append(validServices, service)
 		} else {
 			logs.Warnf("Invalid/unsupported namespace: %s", service)
@@ -48,8 +49,14 @@ func (c *CloudEyeCollector) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 
+	guard := newSeriesGuard(c.cfg.Global.SeriesLimits, c.client.ProjectName)
 	for _, namespace := range c.services {
+		if !registry.IsBuiltin(namespace) && !namespaceHasMetrics(c.client, c.cfg, namespace) {
+			continue
+		}
 		metricData := ExportMetricValuesBatch(c.client, c.cfg, namespace, c.client.ProjectName)
+		// Enforce cardinality guardrails before publishing
+		metricData = guard.apply(namespace, metricData)
 		// Keep track of seen metrics to avoid duplicates
 		seenMetrics := make(map[string]struct{})
 		for _, m := range metricData {
@@ -70,8 +77,8 @@ func (c *CloudEyeCollector) Collect(ch chan<- prometheus.Metric) {
 			// Define constant labels and values
 			constantLabels := []string{constants.LabelResourceID, constants.LabelResourceName, constants.LabelUnit}
 			constantValues := []string{resourceID, resourceName, unit}
-			// Create metric name using namespace mapping
-			metricName := createMetricName(namespace, m.MetricName)
+			// Create metric name using namespace mapping (or the custom namespace alias)
+			metricName := createMetricName(c.cfg.Global.MetricPrefix(namespace), m.MetricName)
 			// Check for duplicates
 			labelKey := fmt.Sprintf("%s-%s-%s-%s-%s", metricName, resourceID, resourceName, unit, m.MetricName)
 			if _, exists := seenMetrics[labelKey]; exists {
@@ -92,15 +99,6 @@ func (c *CloudEyeCollector) Collect(ch chan<- prometheus.Metric) {
 
 // Helper functions
 
-func isValidNamespace(namespace string) bool {
-	for _, validNs := range constants.AllNamespaces {
-		if namespace == validNs {
-			return true
-		}
-	}
-	return false
-}
-
 func isConstantLabel(key string) bool {
 	constantLabels := []string{constants.LabelResourceID, constants.LabelResourceName, constants.LabelUnit}
 	for _, label := range constantLabels {
@@ -118,10 +116,7 @@ func getValueOrDefault(value, defaultValue string) string {
 	return value
 }
 
-func createMetricName(namespace, metricName string) string {
-	// Convert namespace to service name
-	parts := strings.Split(namespace, ".")
-	service := strings.ToLower(parts[len(parts)-1])
+func createMetricName(prefix, metricName string) string {
 	metricNameLower := strings.ToLower(metricName)
-	return fmt.Sprintf("%s_%s", service, metricNameLower)
+	return fmt.Sprintf("%s_%s", prefix, metricNameLower)
 }
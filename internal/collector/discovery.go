@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/clients"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	cesModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1/model"
+)
+
+// ProjectNamespaces is the discovered namespace set of one project.
+type ProjectNamespaces struct {
+	Account   string         `json:"account"`
+	Region    string         `json:"region"`
+	Project   string         `json:"project"`
+	ProjectID string         `json:"project_id"`
+	Metrics   map[string]int `json:"metrics"` // namespace -> number of metric definitions
+	Refreshed time.Time      `json:"refreshed"`
+}
+
+// Namespaces returns the discovered namespaces, sorted.
+func (p ProjectNamespaces) Namespaces() []string {
+	list := make([]string, 0, len(p.Metrics))
+	for ns := range p.Metrics {
+		list = append(list, ns)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// NamespaceDiscoverer periodically lists all CES metrics of every project in the
+// pool and groups them by namespace.
+type NamespaceDiscoverer struct {
+	cfg      *config.Config
+	pool     *clients.Pool
+	interval time.Duration
+
+	mu        sync.RWMutex
+	byProject map[string]ProjectNamespaces
+}
+
+// NewNamespaceDiscoverer creates a discoverer for the projects in the pool.
+func NewNamespaceDiscoverer(cfg *config.Config, pool *clients.Pool) *NamespaceDiscoverer {
+	interval := time.Duration(cfg.Global.NamespaceDiscovery.RefreshIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = constants.DefaultNamespaceRefreshInterval
+	}
+	return &NamespaceDiscoverer{
+		cfg:       cfg,
+		pool:      pool,
+		interval:  interval,
+		byProject: make(map[string]ProjectNamespaces),
+	}
+}
+
+// Run discovers namespaces immediately and then every interval until ctx is cancelled.
+func (d *NamespaceDiscoverer) Run(ctx context.Context) {
+	logs.Infof("🔄 Namespace discovery enabled, refreshing every %v", d.interval)
+	d.Refresh()
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logs.Info("Namespace discovery stopped")
+			return
+		case <-ticker.C:
+			d.Refresh()
+		}
+	}
+}
+
+// Refresh re-discovers the namespaces of every project in the pool.
+// Projects whose listing fails keep their previous namespaces.
+func (d *NamespaceDiscoverer) Refresh() {
+	current := make(map[string]bool)
+	for _, client := range d.pool.Clients() {
+		current[client.Key()] = true
+		counts, err := d.discover(client)
+		if err != nil {
+			logs.Warnf("Namespace discovery failed for project %s: %v", client.ProjectName, err)
+			continue
+		}
+		found := ProjectNamespaces{
+			Account:   client.Account,
+			Region:    client.Region,
+			Project:   client.ProjectName,
+			ProjectID: client.ProjectID,
+			Metrics:   counts,
+			Refreshed: time.Now(),
+		}
+		logs.Infof("✅ Discovered %d namespaces in project %s: %v", len(counts), client.ProjectName, found.Namespaces())
+		d.mu.Lock()
+		d.byProject[client.Key()] = found
+		d.mu.Unlock()
+	}
+	// Forget projects that left the pool
+	d.mu.Lock()
+	for key := range d.byProject {
+		if !current[key] {
+			delete(d.byProject, key)
+		}
+	}
+	d.mu.Unlock()
+}
+
+// discover lists all metric definitions of a project and counts them per namespace.
+func (d *NamespaceDiscoverer) discover(client *clients.Clients) (map[string]int, error) {
+	limit := int32(d.cfg.Global.MetricQueryPageLimit)
+	req := &cesModel.ListMetricsRequest{Limit: &limit}
+	filter := &d.cfg.Global.NamespaceDiscovery.PatternFilter
+	retryConfig := RetryConfigFromConfig(d.cfg)
+	counts := make(map[string]int)
+	for {
+		resp, err := withRetry(
+			func() (*cesModel.ListMetricsResponse, error) {
+				return client.CloudEyeV1.ListMetrics(req)
+			},
+			retryConfig,
+			fmt.Sprintf("list all metrics in project %s", client.ProjectName),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Metrics == nil || len(*resp.Metrics) == 0 {
+			break
+		}
+		for _, m := range *resp.Metrics {
+			if m.Namespace != "" && filter.Allows(m.Namespace) {
+				counts[m.Namespace]++
+			}
+		}
+		if resp.MetaData == nil || resp.MetaData.Marker == "" {
+			break
+		}
+		req.Start = Ptr(resp.MetaData.Marker)
+	}
+	return counts, nil
+}
+
+// Namespaces returns the discovered namespaces of a project, or nil if none were discovered yet.
+func (d *NamespaceDiscoverer) Namespaces(client *clients.Clients) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	found, ok := d.byProject[client.Key()]
+	if !ok {
+		return nil
+	}
+	return found.Namespaces()
+}
+
+// Snapshot returns the discovered namespaces of all projects, keyed by project key.
+func (d *NamespaceDiscoverer) Snapshot() map[string]ProjectNamespaces {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	snapshot := make(map[string]ProjectNamespaces, len(d.byProject))
+	for key, found := range d.byProject {
+		snapshot[key] = found
+	}
+	return snapshot
+}
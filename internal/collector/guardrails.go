@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons reported by cloudeye_series_dropped_total
+const (
+	dropReasonNamespaceLimit = "namespace_limit"
+	dropReasonProjectLimit   = "project_limit"
+)
+
+var (
+	seriesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudeye_series_dropped_total",
+			Help: "Number of series dropped by the exporter's cardinality guardrails.",
+		},
+		[]string{"namespace", "project", "reason"},
+	)
+	// guardrailWarnings ensures each limit violation is only logged once
+	guardrailWarnings sync.Map
+)
+
+// RegisterSelfMetrics registers the exporter's own metrics on the given registry.
+func RegisterSelfMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(seriesDropped)
+}
+
+// seriesGuard enforces series limits for a single collection of one project.
+type seriesGuard struct {
+	limits       config.SeriesLimits
+	project      string
+	projectCount int
+}
+
+func newSeriesGuard(limits config.SeriesLimits, project string) *seriesGuard {
+	return &seriesGuard{limits: limits, project: project}
+}
+
+// apply truncates long label values and drops series beyond the namespace and
+// project limits. Series are kept in sorted key order so truncation is deterministic.
+func (g *seriesGuard) apply(namespace string, exports []MetricExport) []MetricExport {
+	g.truncateLabelValues(namespace, exports)
+
+	keys := make([]string, len(exports))
+	for i, m := range exports {
+		keys[i] = seriesKey(m)
+	}
+	unique := uniqueSorted(keys)
+
+	limit := len(unique)
+	reason := ""
+	if nsLimit := g.namespaceLimit(namespace); nsLimit > 0 && nsLimit < limit {
+		limit, reason = nsLimit, dropReasonNamespaceLimit
+	}
+	if g.limits.MaxSeriesPerProject > 0 {
+		remaining := g.limits.MaxSeriesPerProject - g.projectCount
+		if remaining < 0 {
+			remaining = 0
+		}
+		if remaining < limit {
+			limit, reason = remaining, dropReasonProjectLimit
+		}
+	}
+	g.projectCount += limit
+	if limit == len(unique) {
+		return exports
+	}
+
+	kept := make(map[string]struct{}, limit)
+	for _, k := range unique[:limit] {
+		kept[k] = struct{}{}
+	}
+	order := make([]int, 0, len(exports))
+	for i := range exports {
+		if _, ok := kept[keys[i]]; ok {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]] < keys[order[b]] })
+	result := make([]MetricExport, 0, len(order))
+	for _, i := range order {
+		result = append(result, exports[i])
+	}
+
+	dropped := len(unique) - limit
+	seriesDropped.WithLabelValues(namespace, g.project, reason).Add(float64(dropped))
+	g.warnOnce(reason+"|"+namespace+"|"+g.project, func() {
+		logs.Warnf("⚠️ Series limit (%s) exceeded in namespace %s for project %s: dropped %d of %d series; highest-cardinality label is %q",
+			reason, namespace, g.project, dropped, len(unique), highestCardinalityLabel(exports))
+	})
+	return result
+}
+
+func (g *seriesGuard) namespaceLimit(namespace string) int {
+	if limit, ok := g.limits.Namespaces[namespace]; ok {
+		return limit
+	}
+	return g.limits.MaxSeriesPerNamespace
+}
+
+func (g *seriesGuard) truncateLabelValues(namespace string, exports []MetricExport) {
+	max := g.limits.MaxLabelValueLength
+	if max <= 0 {
+		return
+	}
+	for _, m := range exports {
+		for k, v := range m.Labels {
+			if runes := []rune(v); len(runes) > max {
+				m.Labels[k] = string(runes[:max])
+				g.warnOnce("length|"+namespace+"|"+k, func() {
+					logs.Warnf("⚠️ Label %q in namespace %s exceeds %d characters; values are truncated", k, namespace, max)
+				})
+			}
+		}
+	}
+}
+
+func (g *seriesGuard) warnOnce(key string, warn func()) {
+	if _, loaded := guardrailWarnings.LoadOrStore(key, struct{}{}); !loaded {
+		warn()
+	}
+}
+
+// seriesKey identifies a series by metric name and sorted label pairs.
+func seriesKey(m MetricExport) string {
+	pairs := make([]string, 0, len(m.Labels))
+	for k, v := range m.Labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+	return m.MetricName + "|" + strings.Join(pairs, "|")
+}
+
+func uniqueSorted(keys []string) []string {
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// highestCardinalityLabel returns the label with the most distinct values.
+func highestCardinalityLabel(exports []MetricExport) string {
+	values := make(map[string]map[string]struct{})
+	for _, m := range exports {
+		for k, v := range m.Labels {
+			if values[k] == nil {
+				values[k] = make(map[string]struct{})
+			}
+			values[k][v] = struct{}{}
+		}
+	}
+	best, bestCount := "", 0
+	for k, vs := range values {
+		if len(vs) > bestCount || (len(vs) == bestCount && k < best) {
+			best, bestCount = k, len(vs)
+		}
+	}
+	return best
+}
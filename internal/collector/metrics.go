@@ -14,6 +14,7 @@ import (
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/registry"
 	cesModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1/model"
 )
 
@@ -175,6 +176,14 @@ func ExportMetricValuesBatch(client *clients.Clients, cfg *config.Config, namesp
 		logs.Warnf("No metrics found in namespace %s in project %s", namespace, projectName)
 		return nil
 	}
+
+	// Apply per-namespace metric and resource filters before querying time series
+	metrics = filterMetricDefinitions(metrics, cfg, namespace)
+	metrics = filterMetricsByResource(client, metrics, cfg, namespace)
+	if len(metrics) == 0 {
+		logs.Warnf("All metrics in namespace %s were excluded by metric/resource filters", namespace)
+		return nil
+	}
 	logs.Infof("Listed %d metrics in namespace %s in project %s", len(metrics), namespace, projectName)
 
 	// Fetch time series data
@@ -192,6 +201,9 @@ func ExportMetricValuesBatch(client *clients.Clients, cfg *config.Config, namesp
 	// Process and enrich metrics
 	results := processMetrics(client, cfg, namespace, batchData)
 
+	// Apply relabel_configs so every consumer sees the same series
+	results = applyRelabelConfigs(results, cfg, namespace)
+
 	// Get unique metrics and log count
 	uniqueCount := logUniqueMetricsCount(results, namespace)
 	logs.Infof("Exported %d metric series for namespace %s", uniqueCount, namespace)
@@ -258,10 +270,13 @@ func processMetrics(client *clients.Clients, cfg *config.Config, namespace strin
       // Remove leading/trailing underscores
       m.MetricName = strings.Trim(m.MetricName, "_")
       // Extract and enrich labels
-      labels, resourceID := extractLabelsAndResourceID(m, namespace)
+      labels, resourceID := extractLabelsAndResourceID(m, namespace, cfg)
       labels, resourceID = handleEVSIfNeeded(labels, resourceID, namespace, client)
-      labels = handleOBSIfNeeded(labels, m, namespace, client, RetryConfigFromConfig(cfg))
+      labels = handleOBSIfNeeded(labels, m, namespace, client, cfg)
       labels = enrichWithRMSIfNeeded(labels, resourceID, namespace, client, cfg, RetryConfigFromConfig(cfg))
+      // Every series carries the account and region it was collected from
+      labels[constants.LabelRegion] = client.Region
+      labels[constants.LabelAccount] = client.Account
       // Ensure resource_name exists
       if _, exists := labels[constants.LabelResourceName]; !exists {
         labels[constants.LabelResourceName] = constants.ResourceIDUnknown
@@ -326,6 +341,22 @@ func FetchAllMetricDefinitions(client *clients.Clients, namespace string, cfg *c
 	return result, nil
 }
 
+// filterMetricDefinitions drops metric definitions rejected by the namespace's metric filter.
+func filterMetricDefinitions(metrics []cesModel.MetricInfoList, cfg *config.Config, namespace string) []cesModel.MetricInfoList {
+	filter, ok := cfg.Global.MetricFilters[namespace]
+	if !ok {
+		return metrics
+	}
+	filtered := make([]cesModel.MetricInfoList, 0, len(metrics))
+	for _, m := range metrics {
+		if filter.Allows(m.MetricName) {
+			filtered = append(filtered, m)
+		}
+	}
+	logs.Debugf("Metric filter kept %d of %d metric definitions in namespace %s", len(filtered), len(metrics), namespace)
+	return filtered
+}
+
 func fetchMetricTimeSeries(client *clients.Clients, metrics []cesModel.MetricInfoList, cfg *config.Config, from, to int64, period string) (*[]cesModel.BatchMetricData, error) {
 	batchMetrics := buildBatchMetrics(metrics)
 	if len(batchMetrics) == 0 {
@@ -334,8 +365,8 @@ func fetchMetricTimeSeries(client *clients.Clients, metrics []cesModel.MetricInf
 	}
 
 	maxBatchSize := cfg.Global.MetricQueryBatchSize
-	if maxBatchSize <= 0 || maxBatchSize > 10 {
-		maxBatchSize = 10 // Default to API limit
+	if maxBatchSize <= 0 || maxBatchSize > constants.MaxMetricBatchSize {
+		maxBatchSize = constants.MaxMetricBatchSize // Default to API limit
 	}
 	retryConfig := RetryConfigFromConfig(cfg)
 
@@ -430,12 +461,21 @@ func buildBatchMetrics(metrics []cesModel.MetricInfoList) []cesModel.MetricInfo
 // -----------------------------
 // Label/Resource Enrichment
 // -----------------------------
-func extractLabelsAndResourceID(m cesModel.BatchMetricData, namespace string) (map[string]string, string) {
+func extractLabelsAndResourceID(m cesModel.BatchMetricData, namespace string, cfg *config.Config) (map[string]string, string) {
 	labelBuilder := NewLabelBuilder(namespace)
 	labelBuilder.AddDimensions(m.Dimensions)
 	labels := labelBuilder.Build()
-	// Find resource ID
-	resourceID := findResourceID(labels)
+	// Find resource ID, using the configured dimensions of a custom namespace first
+	resourceID := customResourceID(labels, cfg.Global.CustomNamespaces[namespace])
+	if resourceID == "" {
+		// Then the default resource dimension of a built-in namespace
+		if info, ok := registry.Lookup(namespace); ok && info.ResourceDimension != "" {
+			resourceID = labels[strings.ToLower(info.ResourceDimension)]
+		}
+	}
+	if resourceID == "" {
+		resourceID = findResourceID(labels)
+	}
 	// Handle special cases for OBS
 	if resourceID == "" {
 		resourceID = handleSpecialResourceID(labels, m, namespace)
@@ -448,6 +488,22 @@ func extractLabelsAndResourceID(m cesModel.BatchMetricData, namespace string) (m
 	return labels, resourceID
 }
 
+// customResourceID maps the configured dimensions of a custom namespace to resource_id/resource_name.
+func customResourceID(labels map[string]string, custom *config.CustomNamespace) string {
+	if custom == nil {
+		return ""
+	}
+	if custom.ResourceNameDimension != "" {
+		if name := labels[strings.ToLower(custom.ResourceNameDimension)]; name != "" {
+			labels[constants.LabelResourceName] = name
+		}
+	}
+	if custom.ResourceDimension == "" {
+		return ""
+	}
+	return labels[strings.ToLower(custom.ResourceDimension)]
+}
+
 func findResourceID(labels map[string]string) string {
 	for key, value := range labels {
 		if strings.HasSuffix(key, "_id") &&
@@ -499,6 +555,9 @@ func enrichWithRMSIfNeeded(labels map[string]string, resourceID, namespace strin
 	if !shouldEnrichWithRMS(client, resourceID, namespace) {
 		return labels
 	}
+	if !usesRMSEnrichment(namespace, cfg) {
+		return labels
+	}
 	rmsResource, err := withRetry(
 		func() (map[string]string, error) {
 			return client.RMS.GetResourceByID(resourceID, "")
@@ -513,7 +572,21 @@ func enrichWithRMSIfNeeded(labels map[string]string, resourceID, namespace strin
 	if rmsResource == nil {
 		return labels
 	}
-	return applyRMSEnrichment(labels, rmsResource, client, cfg)
+	labels = applyRMSEnrichment(labels, rmsResource, client, cfg)
+	return applyCCEEnrichment(labels, rmsResource, namespace, cfg)
+}
+
+// usesRMSEnrichment reports whether RMS lookups are enabled for a namespace.
+// Custom namespaces usually carry application IDs that RMS does not know, so they opt in.
+// Namespaces that are neither built-in nor custom (e.g. discovered ones) keep RMS enrichment.
+func usesRMSEnrichment(namespace string, cfg *config.Config) bool {
+	if custom, ok := cfg.Global.CustomNamespaces[namespace]; ok {
+		return custom.RMSEnrichment
+	}
+	if !registry.IsBuiltin(namespace) {
+		return true
+	}
+	return registry.HasEnricher(namespace, registry.EnricherRMS)
 }
 
 func shouldEnrichWithRMS(client *clients.Clients, resourceID, namespace string) bool {
@@ -538,14 +611,10 @@ func applyRMSEnrichment(labels map[string]string, rmsResource map[string]string,
 		labels[constants.LabelProjectName] = client.ProjectName
 	}
 	if cfg.Global.ExportRMSLabels["domain_name"] {
-		labels["domain_name"] = cfg.Auth.DomainName
+		labels["domain_name"] = client.DomainName
 	}
 	if cfg.Global.ExportRMSLabels["tags"] {
-		for key, value := range rmsResource {
-			if strings.HasPrefix(key, "tag_") && value != "" {
-				labels[key] = value
-			}
-		}
+		cfg.Global.TagPolicy.Apply(labels, extractRMSTags(rmsResource))
 	}
 	if rmsID := rmsResource["id"]; rmsID != "" {
 		labels[constants.LabelResourceID] = rmsID
@@ -553,8 +622,19 @@ func applyRMSEnrichment(labels map[string]string, rmsResource map[string]string,
 	return labels
 }
 
+// extractRMSTags returns the raw tags of an RMS resource (stored as "tag_<key>").
+func extractRMSTags(rmsResource map[string]string) map[string]string {
+	tags := make(map[string]string)
+	for key, value := range rmsResource {
+		if strings.HasPrefix(key, "tag_") {
+			tags[strings.TrimPrefix(key, "tag_")] = value
+		}
+	}
+	return tags
+}
+
 func handleEVSIfNeeded(labels map[string]string, resourceID, namespace string, client *clients.Clients) (map[string]string, string) {
-	if !strings.Contains(namespace, "EVS") {
+	if !registry.HasEnricher(namespace, registry.EnricherEVS) {
 		return labels, resourceID
 	}
 	lastDash := strings.LastIndex(resourceID, "-")
@@ -592,14 +672,14 @@ func lookupEVSID(client *clients.Clients, vmID, device string) (string, string)
 	return "", ""
 }
 
-func handleOBSIfNeeded(labels map[string]string, m cesModel.BatchMetricData, namespace string, client *clients.Clients, retryConfig *RetryConfig) map[string]string {
-	if namespace != constants.NamespaceOBS {
+func handleOBSIfNeeded(labels map[string]string, m cesModel.BatchMetricData, namespace string, client *clients.Clients, cfg *config.Config) map[string]string {
+	if !registry.HasEnricher(namespace, registry.EnricherOBS) {
 		return labels
 	}
 	bucketName := getBucketNameFromDimensions(m.Dimensions)
 	if bucketName != "" {
 		labels["bucket_name"] = bucketName
-		return enrichOBSBucketInfo(labels, bucketName, client)
+		return enrichOBSBucketInfo(labels, bucketName, client, cfg)
 	}
 	// Handle service-level metrics
 	if tenantID, exists := labels["tenant_id"]; exists && labels[constants.LabelResourceID] == tenantID {
@@ -611,16 +691,14 @@ func handleOBSIfNeeded(labels map[string]string, m cesModel.BatchMetricData, nam
 	return labels
 }
 
-func enrichOBSBucketInfo(labels map[string]string, bucketName string, client *clients.Clients) map[string]string {
+func enrichOBSBucketInfo(labels map[string]string, bucketName string, client *clients.Clients, cfg *config.Config) map[string]string {
 	if client.OBS == nil {
 		return labels
 	}
 	// Try to get bucket tags
 	if tags, err := client.OBS.GetBucketTags(bucketName); err == nil {
-		for k, v := range tags {
-			labels["tag_"+k] = v
-		}
-		logs.Debugf("Added %d bucket tags to labels for bucket %s", len(tags), bucketName)
+		added := cfg.Global.TagPolicy.Apply(labels, tags)
+		logs.Debugf("Added %d of %d bucket tags to labels for bucket %s", added, len(tags), bucketName)
 	} else {
 		logs.Warnf("Could not fetch tags for OBS bucket %s: %v", bucketName, err)
 	}
@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/clients"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	cesModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1/model"
+)
+
+// namespaceChecks caches, per project and namespace, whether CES has metrics in a
+// non-built-in namespace, so custom namespaces are not re-validated on every scrape.
+var namespaceChecks sync.Map
+
+type namespaceCheck struct {
+	hasMetrics bool
+	checked    time.Time
+}
+
+// namespaceHasMetrics asks CES whether the project has any metric in the namespace.
+// Errors are not cached, so the namespace is checked again on the next scrape.
+func namespaceHasMetrics(client *clients.Clients, cfg *config.Config, namespace string) bool {
+	key := client.Key() + "|" + namespace
+	if v, ok := namespaceChecks.Load(key); ok {
+		check := v.(namespaceCheck)
+		if time.Since(check.checked) < constants.NamespaceValidationTTL {
+			return check.hasMetrics
+		}
+	}
+	limit := int32(1)
+	req := &cesModel.ListMetricsRequest{
+		Limit:     &limit,
+		Namespace: Ptr(namespace),
+	}
+	resp, err := withRetry(
+		func() (*cesModel.ListMetricsResponse, error) {
+			return client.CloudEyeV1.ListMetrics(req)
+		},
+		RetryConfigFromConfig(cfg),
+		fmt.Sprintf("validate namespace %s", namespace),
+	)
+	if err != nil {
+		logs.Warnf("⚠️ Could not validate namespace %s in project %s: %v", namespace, client.ProjectName, err)
+		return false
+	}
+	hasMetrics := resp.Metrics != nil && len(*resp.Metrics) > 0
+	if !hasMetrics {
+		logs.Warnf("⚠️ Cloud Eye has no metrics in namespace %s for project %s, skipping", namespace, client.ProjectName)
+	}
+	namespaceChecks.Store(key, namespaceCheck{hasMetrics: hasMetrics, checked: time.Now()})
+	return hasMetrics
+}
@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/relabel"
+)
+
+// namespaceWildcard selects settings that apply to every namespace.
+const namespaceWildcard = "*"
+
+// applyRelabelConfigs runs the configured relabel rules on each export.
+// The Cloud Eye metric name (without service prefix) is exposed as __name__.
+func applyRelabelConfigs(exports []MetricExport, cfg *config.Config, namespace string) []MetricExport {
+	rules := append(append([]*relabel.Config{}, cfg.Global.RelabelConfigs[namespaceWildcard]...), cfg.Global.RelabelConfigs[namespace]...)
+	if len(rules) == 0 {
+		return exports
+	}
+	out := make([]MetricExport, 0, len(exports))
+	for _, m := range exports {
+		labels := cloneMap(m.Labels)
+		labels[relabel.MetricNameLabel] = m.MetricName
+		labels = relabel.Process(labels, rules...)
+		if labels == nil {
+			continue
+		}
+		if name := labels[relabel.MetricNameLabel]; name != "" {
+			m.MetricName = name
+		}
+		delete(labels, relabel.MetricNameLabel)
+		m.Labels = labels
+		out = append(out, m)
+	}
+	if dropped := len(exports) - len(out); dropped > 0 {
+		logs.Debugf("Relabeling dropped %d of %d series in namespace %s", dropped, len(exports), namespace)
+	}
+	return out
+}
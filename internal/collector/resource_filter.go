@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/clients"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	cesModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1/model"
+)
+
+// resourceFiltersFor returns the wildcard and namespace-specific resource filters.
+func resourceFiltersFor(cfg *config.Config, namespace string) []*config.ResourceFilter {
+	var filters []*config.ResourceFilter
+	for _, key := range []string{namespaceWildcard, namespace} {
+		if f := cfg.Global.ResourceFilters[key]; f != nil {
+			filters = append(filters, f)
+		}
+	}
+	return filters
+}
+
+// filterMetricsByResource drops metric definitions whose resources are excluded by
+// resource_filters, so excluded resources never cost a time-series query.
+func filterMetricsByResource(client *clients.Clients, metrics []cesModel.MetricInfoList, cfg *config.Config, namespace string) []cesModel.MetricInfoList {
+	filters := resourceFiltersFor(cfg, namespace)
+	if len(filters) == 0 {
+		return metrics
+	}
+	inventory := loadInventoryIfNeeded(client, filters)
+	filtered := make([]cesModel.MetricInfoList, 0, len(metrics))
+	for _, m := range metrics {
+		if allowsMetricResource(m, filters, inventory) {
+			filtered = append(filtered, m)
+		}
+	}
+	logs.Debugf("Resource filters kept %d of %d metric definitions in namespace %s", len(filtered), len(metrics), namespace)
+	return filtered
+}
+
+func loadInventoryIfNeeded(client *clients.Clients, filters []*config.ResourceFilter) map[string]map[string]string {
+	for _, f := range filters {
+		if !f.NeedsInventory() {
+			continue
+		}
+		if client.RMS == nil {
+			logs.Warnf("Resource name/tag filters configured but no RMS client available for project %s", client.ProjectName)
+			return nil
+		}
+		inventory, err := client.RMS.GetInventory()
+		if err != nil {
+			logs.Warnf("Failed to load RMS inventory for resource filters: %v", err)
+			return nil
+		}
+		return inventory
+	}
+	return nil
+}
+
+func allowsMetricResource(m cesModel.MetricInfoList, filters []*config.ResourceFilter, inventory map[string]map[string]string) bool {
+	if len(m.Dimensions) == 0 {
+		return true
+	}
+	resourceID := m.Dimensions[0].Value
+	for _, f := range filters {
+		for _, dim := range m.Dimensions {
+			if pf, ok := f.Dimensions[strings.ToLower(dim.Name)]; ok && !pf.Allows(dim.Value) {
+				return false
+			}
+		}
+		if !f.ResourceIDs.Allows(resourceID) {
+			return false
+		}
+		if !f.NeedsInventory() {
+			continue
+		}
+		resource, ok := findInventoryResource(m.Dimensions, inventory)
+		if !ok {
+			if f.RequiresInventoryMatch() {
+				return false
+			}
+			continue
+		}
+		if !f.AllowsResource(resource) {
+			return false
+		}
+	}
+	return true
+}
+
+// findInventoryResource returns the first RMS resource matching any dimension value.
+func findInventoryResource(dims []cesModel.MetricsDimension, inventory map[string]map[string]string) (map[string]string, bool) {
+	for _, dim := range dims {
+		if resource, ok := inventory[dim.Value]; ok {
+			return resource, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/credentials"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
+)
+
+// AgencyConfig assumes an IAM agency, possibly in another domain, to obtain
+// temporary credentials that are renewed before they expire.
+type AgencyConfig struct {
+	Name string `yaml:"name"`
+	// DomainID is the domain that created the agency; collection runs in this domain
+	DomainID        string `yaml:"domain_id"`
+	DomainName      string `yaml:"domain_name,omitempty"`
+	DurationSeconds int    `yaml:"duration_seconds,omitempty"`
+}
+
+func (a *AgencyConfig) validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("agency.name is required")
+	}
+	if a.DomainID == "" {
+		return fmt.Errorf("agency.domain_id is required")
+	}
+	if a.DurationSeconds == 0 {
+		a.DurationSeconds = constants.DefaultAgencyDurationSeconds
+	}
+	if a.DurationSeconds < 900 || a.DurationSeconds > 86400 {
+		return fmt.Errorf("agency.duration_seconds must be between 900 and 86400, got %d", a.DurationSeconds)
+	}
+	return nil
+}
+
+// Credentials returns the account's credential source, shared by all its clients.
+func (a CloudAuth) Credentials() *credentials.Source {
+	if a.source != nil {
+		return a.source
+	}
+	return credentials.NewStatic(a.DomainName, a.staticCredentials())
+}
+
+func (a CloudAuth) staticCredentials() credentials.Credentials {
+	return credentials.Credentials{AccessKey: a.AccessKey, SecretKey: a.SecretKey, SecurityToken: a.SecurityToken}
+}
+
+// initCredentials creates the account's credential source. With an agency, the
+// configured keys are only used to assume it and everything else runs in the
+// agency's domain with the temporary credentials.
+func initCredentials(name string, auth *CloudAuth) error {
+	if auth.source == nil {
+		auth.source = credentials.NewStatic(name, auth.staticCredentials())
+	}
+	if auth.Agency == nil {
+		return nil
+	}
+	if err := auth.Agency.validate(); err != nil {
+		return err
+	}
+	base := *auth
+	base.source = auth.Credentials()
+	if base.Region == "" && len(base.Regions) > 0 {
+		base = base.ForRegion(base.Regions[0])
+	}
+	agency := *auth.Agency
+	src, err := credentials.NewRenewing(name, func() (credentials.Credentials, error) {
+		return assumeAgency(base, agency)
+	}, constants.CredentialRenewBefore)
+	if err != nil {
+		return fmt.Errorf("assuming agency %s in domain %s: %w", agency.Name, agency.DomainID, err)
+	}
+	auth.source = src
+	auth.DomainID = agency.DomainID
+	if agency.DomainName != "" {
+		auth.DomainName = agency.DomainName
+	}
+	return nil
+}
+
+// assumeAgency obtains temporary credentials for the agency using the base credentials.
+func assumeAgency(base CloudAuth, agency AgencyConfig) (credentials.Credentials, error) {
+	client, err := newIamClient(base)
+	if err != nil {
+		return credentials.Credentials{}, err
+	}
+	duration := int32(agency.DurationSeconds)
+	assumeRole := &model.IdentityAssumerole{
+		AgencyName:      agency.Name,
+		DomainId:        &agency.DomainID,
+		DurationSeconds: &duration,
+	}
+	req := &model.CreateTemporaryAccessKeyByAgencyRequest{
+		Body: &model.CreateTemporaryAccessKeyByAgencyRequestBody{
+			Auth: &model.AgencyAuth{
+				Identity: &model.AgencyAuthIdentity{
+					Methods:    []model.AgencyAuthIdentityMethods{model.GetAgencyAuthIdentityMethodsEnum().ASSUME_ROLE},
+					AssumeRole: assumeRole,
+				},
+			},
+		},
+	}
+	resp, err := client.CreateTemporaryAccessKeyByAgency(req)
+	if err != nil {
+		if se, ok := err.(*sdkerr.ServiceResponseError); ok {
+			return credentials.Credentials{}, fmt.Errorf("IAM API error: %s", se.ErrorMessage)
+		}
+		return credentials.Credentials{}, err
+	}
+	if resp.Credential == nil {
+		return credentials.Credentials{}, fmt.Errorf("IAM returned no credential")
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, resp.Credential.ExpiresAt)
+	if err != nil {
+		return credentials.Credentials{}, fmt.Errorf("invalid expiry %q: %w", resp.Credential.ExpiresAt, err)
+	}
+	return credentials.Credentials{
+		AccessKey:     resp.Credential.Access,
+		SecretKey:     resp.Credential.Secret,
+		SecurityToken: resp.Credential.Securitytoken,
+		ExpiresAt:     expiresAt,
+	}, nil
+}
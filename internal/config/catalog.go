@@ -0,0 +1,96 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+)
+
+// serviceCatalog holds the built-in endpoint templates for all OTC services.
+// "{region}" and "{domain}" are substituted per region.
+var serviceCatalog = map[string]string{
+	constants.ServiceIAM:         "https://iam.{region}.{domain}",
+	constants.ServiceRMS:         "https://rms.{region}.{domain}",
+	constants.ServiceCES:         "https://ces.{region}.{domain}",
+	constants.NamespaceAGT:       "https://ecs.{region}.{domain}",
+	constants.NamespaceECS:       "https://ecs.{region}.{domain}",
+	constants.NamespaceBMS:       "https://ecs.{region}.{domain}",
+	constants.NamespaceAS:        "https://as.{region}.{domain}",
+	constants.NamespaceEVS:       "https://evs.{region}.{domain}",
+	constants.NamespaceOBS:       "https://obs.{region}.{domain}",
+	constants.NamespaceSFS:       "https://sfs.{region}.{domain}",
+	constants.NamespaceEFS:       "https://efs.{region}.{domain}",
+	constants.NamespaceCBR:       "https://cbr.{region}.{domain}",
+	constants.NamespaceVPC:       "https://vpc.{region}.{domain}",
+	constants.NamespaceELB:       "https://elb.{region}.{domain}",
+	constants.NamespaceDC:        "https://dcaas.{region}.{domain}",
+	constants.NamespaceNAT:       "https://nat.{region}.{domain}",
+	constants.NamespaceER:        "https://er.{region}.{domain}",
+	constants.NamespaceVPN:       "https://vpn.{region}.{domain}",
+	constants.NamespaceWAF:       "https://waf.{region}.{domain}",
+	constants.NamespaceCFW:       "https://cfw.{region}.{domain}",
+	constants.NamespaceDMS:       "https://dms.{region}.{domain}",
+	constants.NamespaceDCS:       "https://dcs.{region}.{domain}",
+	constants.NamespaceAPIC:      "https://apig.{region}.{domain}",
+	constants.NamespaceRDS:       "https://rds.{region}.{domain}",
+	constants.NamespaceDDS:       "https://dds.{region}.{domain}",
+	constants.NamespaceNoSQL:     "https://gaussdb-nosql.{region}.{domain}",
+	constants.NamespaceGaussDB:   "https://gaussdb-mysql.{region}.{domain}",
+	constants.NamespaceGaussDBV5: "https://gaussdb-opengauss.{region}.{domain}",
+	constants.NamespaceDWS:       "https://dws.{region}.{domain}",
+	constants.NamespaceES:        "https://es.{region}.{domain}",
+	constants.NamespaceDAYU:      "https://dayu-dlf.{region}.{domain}",
+	constants.ServiceCTS:         "https://cts.{region}.{domain}",
+	constants.ServiceLTS:         "https://lts.{region}.{domain}",
+}
+
+// regionServiceOverrides holds endpoints that deviate from the catalog template in a region.
+var regionServiceOverrides = map[string]map[string]string{
+	constants.RegionEUCH2: {
+		constants.ServiceIAM: "https://iam-pub.{region}.{domain}",
+	},
+}
+
+// regionDomains maps each OTC region to its API domain.
+var regionDomains = map[string]string{
+	constants.RegionEUDE:  constants.DomainOTC,
+	constants.RegionEUNL:  constants.DomainOTC,
+	constants.RegionEUCH2: constants.DomainSwissCloud,
+}
+
+// IsKnownRegion reports whether the region is part of the built-in catalog.
+func IsKnownRegion(region string) bool {
+	_, ok := regionDomains[region]
+	return ok
+}
+
+// CatalogEndpoints returns the built-in service endpoints for a region.
+// Unknown regions fall back to the public OTC domain scheme.
+func CatalogEndpoints(region string) map[string]string {
+	domain := regionDomain(region)
+	services := make(map[string]string, len(serviceCatalog))
+	for key, tpl := range serviceCatalog {
+		services[key] = expandEndpoint(tpl, region, domain)
+	}
+	for key, tpl := range regionServiceOverrides[region] {
+		services[key] = expandEndpoint(tpl, region, domain)
+	}
+	return services
+}
+
+// regionDomain returns the API domain of a region, defaulting to the public OTC domain.
+func regionDomain(region string) string {
+	if domain, ok := regionDomains[region]; ok {
+		return domain
+	}
+	return constants.DomainOTC
+}
+
+// CatalogEndpoint returns the built-in endpoint of a single service in a region.
+func CatalogEndpoint(region, service string) string {
+	return CatalogEndpoints(region)[service]
+}
+
+func expandEndpoint(tpl, region, domain string) string {
+	return strings.NewReplacer("{region}", region, "{domain}", domain).Replace(tpl)
+}
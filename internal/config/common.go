@@ -1,39 +1,78 @@
 package config
 
 import (
+	"net/http"
+
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/config"
 )
 
-// GetHttpConfig builds an HTTP config with optional proxy support for RMS or any HuaweiCloud SDK client.
+// GetHttpConfig builds the HTTP config for any HuaweiCloud SDK client from the active config.
 func GetHttpConfig() *config.HttpConfig {
+	cfg := Current()
+	if cfg == nil {
+		return (&Global{}).HttpConfig()
+	}
+	return cfg.Global.HttpConfig()
+}
+
+// HttpConfig builds an SDK HTTP config that sends requests through the shared transport
+// (proxy, NO_PROXY, timeouts, keep-alive, TLS). Clients built during a reload use it
+// so they pick up the new settings.
+func (g *Global) HttpConfig() *config.HttpConfig {
 	httpConfig := config.DefaultHttpConfig()
-	if !isProxyConfigured() {
-		logs.Debugf("Proxy not configured; using direct connection.")
-		return httpConfig
+	httpConfig.IgnoreSSLVerification = g.IgnoreSSLVerify
+	httpConfig.Timeout = g.HTTPClient.timeout()
+	httpConfig.HttpTransport = g.Transport()
+	return httpConfig
+}
+
+// NewHTTPClient returns an http.Client on the shared transport for clients that are
+// not built by the HuaweiCloud SDK (OBS). Like the SDK, it does not follow redirects.
+func (g *Global) NewHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: g.Transport(),
+		Timeout:   g.HTTPClient.timeout(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
-	proxy := config.Proxy{
-		Schema: AppConfig.Global.HttpSchema,
-		Host:   AppConfig.Global.HttpHost,
-		Port:   AppConfig.Global.HttpPort,
+}
+
+// Transport returns the transport shared by all clients of this config.
+func (g *Global) Transport() *http.Transport {
+	if g.transport == nil {
+		// Config that was not loaded by LoadConfig (e.g. in validate)
+		transport, err := g.newTransport()
+		if err != nil {
+			logs.Errorf("❌ Invalid HTTP client settings, using defaults: %v", err)
+			transport, _ = (&Global{IgnoreSSLVerify: g.IgnoreSSLVerify}).newTransport()
+		}
+		return transport
 	}
-	if isProxyAuthConfigured() {
-		proxy.Username = AppConfig.Global.UserName
-		proxy.Password = AppConfig.Global.Password
-	} else {
-		logs.Debugf("Proxy authentication not configured; using proxy without auth.")
+	return g.transport
+}
+
+// CloseIdleConnections closes the idle connections of the shared transport,
+// e.g. once a reload has replaced this config.
+func (g *Global) CloseIdleConnections() {
+	if g.transport != nil {
+		g.transport.CloseIdleConnections()
 	}
-	httpConfig.HttpProxy = &proxy
-	httpConfig.IgnoreSSLVerification = AppConfig.Global.IgnoreSSLVerify
-	return httpConfig
 }
 
-func isProxyConfigured() bool {
-	g := AppConfig.Global
+func (g *Global) isProxyConfigured() bool {
 	return g.HttpSchema != "" && g.HttpHost != "" && g.HttpPort > 0
 }
 
-func isProxyAuthConfigured() bool {
-	g := AppConfig.Global
-	return g.UserName != "" && g.Password != ""
+func (g *Global) isProxyAuthConfigured() bool {
+	return g.UserName != "" && g.ProxyPassword() != ""
+}
+
+// httpConfig returns the HTTP config for the account's IAM calls.
+func (a CloudAuth) httpConfig() *config.HttpConfig {
+	if a.global != nil {
+		return a.global.HttpConfig()
+	}
+	return GetHttpConfig()
 }
@@ -1,18 +1,20 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"regexp"
+	"net/http"
 	"strings"
+	"sync/atomic"
 
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/credentials"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
-	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/relabel"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/secrets"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
 	iam "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
-	"gopkg.in/yaml.v2"
 )
 
 // ---------- Struct Definitions ----------
@@ -22,157 +24,397 @@ type ProjectConfig struct {
 	ID   string `yaml:"id,omitempty"`
 }
 
+// RegionConfig describes one region to collect from, with its own projects
+// and optional endpoint overrides (namespace -> URL, "{region}" is substituted).
+type RegionConfig struct {
+	Name      string            `yaml:"name"`
+	AuthURL   string            `yaml:"auth_url,omitempty"`
+	Projects  []ProjectConfig   `yaml:"projects"`
+	Endpoints map[string]string `yaml:"endpoints,omitempty"`
+	// ProjectSelector picks projects by pattern instead of (or on top of) the explicit list
+	ProjectSelector *ProjectSelector `yaml:"project_selector,omitempty"`
+
+	// discoverAll is set when no explicit project list was configured
+	discoverAll bool
+	// configured keeps the project list as written in the config file
+	configured []ProjectConfig
+}
+
 type CloudAuth struct {
 	Projects   []ProjectConfig `yaml:"projects"`
 	DomainName string          `yaml:"domain_name"`
 	DomainID   string          `yaml:"domain_id"`
 	AccessKey  string          `yaml:"access_key"`
 	SecretKey  string          `yaml:"secret_key"`
-	Region     string          `yaml:"region"`
-	AuthURL    string          `yaml:"auth_url"`
+	// SecurityToken makes access_key/secret_key temporary credentials
+	SecurityToken string `yaml:"security_token,omitempty"`
+	Region        string `yaml:"region"`
+	AuthURL       string `yaml:"auth_url"`
+	// Regions lists all regions to collect from; when empty, Region/Projects/AuthURL are used
+	Regions []RegionConfig `yaml:"regions,omitempty"`
+	// ProjectSelector is the default selector for regions that do not set their own
+	ProjectSelector *ProjectSelector `yaml:"project_selector,omitempty"`
+	// Agency obtains renewing temporary credentials by assuming an IAM agency
+	Agency *AgencyConfig `yaml:"agency,omitempty"`
+
+	// source holds the credentials shared by all clients of the account
+	source *credentials.Source
+	// global carries the outbound HTTP settings for the account's IAM calls
+	global *Global
+}
+
+// CCELabelsConfig maps RMS tags/properties of CCE worker nodes to cce_* labels.
+// Keys are looked up first as tags, then as properties ("metadata.<key>" for ECS metadata).
+type CCELabelsConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Namespaces   []string `yaml:"namespaces,omitempty"`
+	ClusterKeys  []string `yaml:"cluster_keys,omitempty"`
+	NodePoolKeys []string `yaml:"nodepool_keys,omitempty"`
+}
+
+// SeriesLimits caps the number of exported series and label value length (0 = unlimited).
+type SeriesLimits struct {
+	MaxSeriesPerNamespace int            `yaml:"max_series_per_namespace"`
+	MaxSeriesPerProject   int            `yaml:"max_series_per_project"`
+	MaxLabelValueLength   int            `yaml:"max_label_value_length"`
+	Namespaces            map[string]int `yaml:"namespaces,omitempty"`
 }
 
 type Global struct {
-	Port                        string          `yaml:"port"`
-	EnableHTTPS                 bool            `yaml:"enable_https"`
-	HTTPSPort                   string          `yaml:"https_port"`
-	TLSCert                     string          `yaml:"tls_cert"`
-	TLSKey                      string          `yaml:"tls_key"`
-	MetricPath                  string          `yaml:"metric_path"`
-	Namespaces                  string          `yaml:"namespaces"`
-	EndpointsConfPath           string          `yaml:"endpoints_conf_path"`
-	LogsConfPath                string          `yaml:"logs_conf_path"`
-	IgnoreSSLVerify             bool            `yaml:"ignore_ssl_verify"`
-	HttpSchema                  string          `yaml:"proxy_schema"`
-	HttpHost                    string          `yaml:"proxy_host"`
-	HttpPort                    int             `yaml:"proxy_port"`
-	UserName                    string          `yaml:"proxy_username"`
-	Password                    string          `yaml:"proxy_password"`
-	ExportRMSLabels             map[string]bool `yaml:"export_rms_labels"`
-	APIMaxRetries               int             `yaml:"api_max_retries"`
-	APIRetryInitialDelaySeconds int             `yaml:"api_retry_initial_delay_seconds"`
-	APIRetryMaxDelaySeconds     int             `yaml:"api_retry_max_delay_seconds"`
-	APIRetryBackoffMultiplier   float64         `yaml:"api_retry_backoff_multiplier"`
-	MetricQueryPeriodMinutes    int             `yaml:"metric_query_period_minutes"`
-	MetricQueryPageLimit        int             `yaml:"metric_query_page_limit"`
-	MetricQueryWindowMs         int             `yaml:"metric_query_window_ms"`
-	MetricQueryBatchSize        int             `yaml:"metric_query_batch_size"`
+	Port                        string             `yaml:"port"`
+	EnableHTTPS                 bool               `yaml:"enable_https"`
+	HTTPSPort                   string             `yaml:"https_port"`
+	TLSCert                     string             `yaml:"tls_cert"`
+	TLSKey                      string             `yaml:"tls_key"`
+	MetricPath                  string             `yaml:"metric_path"`
+	Namespaces                  string             `yaml:"namespaces"`
+	EndpointsConfPath           string             `yaml:"endpoints_conf_path"`
+	LogsConfPath                string             `yaml:"logs_conf_path"`
+	IgnoreSSLVerify             bool               `yaml:"ignore_ssl_verify"`
+	HttpSchema                  string             `yaml:"proxy_schema"`
+	HttpHost                    string             `yaml:"proxy_host"`
+	HttpPort                    int                `yaml:"proxy_port"`
+	UserName                    string             `yaml:"proxy_username"`
+	Password                    string             `yaml:"proxy_password"`
+	ExportRMSLabels             map[string]bool    `yaml:"export_rms_labels"`
+	APIMaxRetries               int                `yaml:"api_max_retries"`
+	APIRetryInitialDelaySeconds int                `yaml:"api_retry_initial_delay_seconds"`
+	APIRetryMaxDelaySeconds     int                `yaml:"api_retry_max_delay_seconds"`
+	APIRetryBackoffMultiplier   float64            `yaml:"api_retry_backoff_multiplier"`
+	MetricQueryPeriodMinutes    int                `yaml:"metric_query_period_minutes"`
+	MetricQueryPageLimit        int                `yaml:"metric_query_page_limit"`
+	MetricQueryWindowMs         int                `yaml:"metric_query_window_ms"`
+	MetricQueryBatchSize        int                `yaml:"metric_query_batch_size"`
+	EndpointDiscovery           bool               `yaml:"endpoint_discovery"`
+	ProjectDiscovery            ProjectDiscovery   `yaml:"project_discovery"`
+	NamespaceDiscovery          NamespaceDiscovery `yaml:"namespace_discovery"`
+
+	// Secret provider chain: "env:VAR", "${VAR}", "file:/path" or "secretstore:key"
+	SecretStore                  *secrets.StoreConfig `yaml:"secret_store,omitempty"`
+	SecretRefreshIntervalSeconds int                  `yaml:"secret_refresh_interval_seconds"`
+	// proxyPassword is the watched proxy_password
+	proxyPassword *secrets.Value
+
+	// HTTPServer holds the timeouts and shutdown behaviour of the exporter's own server
+	HTTPServer HTTPServerConfig `yaml:"http_server"`
+	// WebConfigFile is an exporter-toolkit style web config (TLS, basic auth, bearer tokens)
+	WebConfigFile string `yaml:"web_config_file"`
+	// HTTPClient tunes the outbound transport shared by all cloud API clients
+	HTTPClient HTTPClientConfig `yaml:"http_client"`
+	// transport is built once per loaded config and shared by every client
+	transport *http.Transport
+
+	// Label enrichment and series shaping
+	CCELabels    CCELabelsConfig `yaml:"cce_labels"`
+	TagPolicy    TagPolicy       `yaml:"tag_policy"`
+	SeriesLimits SeriesLimits    `yaml:"series_limits"`
+	// MetricFilters maps a namespace to metric include/exclude patterns
+	MetricFilters map[string]*PatternFilter `yaml:"metric_filters"`
+	// ResourceFilters maps a namespace (or "*" for all) to dimension/resource filters
+	ResourceFilters map[string]*ResourceFilter `yaml:"resource_filters"`
+	// RelabelConfigs maps a namespace (or "*" for all) to Prometheus-style relabel rules
+	RelabelConfigs map[string][]*relabel.Config `yaml:"relabel_configs"`
+	// CustomNamespaces describes user-defined CES namespaces (e.g. "MYAPP.BILLING")
+	CustomNamespaces map[string]*CustomNamespace `yaml:"custom_namespaces"`
+}
+
+// AccountConfig is a named account profile with its own credentials, domain, projects and regions.
+type AccountConfig struct {
+	Name      string `yaml:"name"`
+	CloudAuth `yaml:",inline"`
 }
 
 type Config struct {
 	Auth   CloudAuth `yaml:"auth"`
 	Global Global    `yaml:"global"`
+	// Accounts lists all account profiles; when empty, Auth is used as the only account
+	Accounts []AccountConfig `yaml:"accounts,omitempty"`
+
+	// skipped holds why LoadConfig left accounts out
+	skipped []error
 }
 
 type ProjectInfo struct {
-	Name string
-	ID   string
+	Name        string
+	ID          string
+	Description string
+}
+
+// current is the active config; a reload swaps it atomically.
+var current atomic.Pointer[Config]
+
+// Current returns the active config, or nil before the first one is published.
+func Current() *Config {
+	return current.Load()
 }
 
-var AppConfig *Config
+// Publish makes cfg the active config. Loading does not publish, so a reload
+// can validate a new config and build its clients before switching to it.
+func Publish(cfg *Config) {
+	current.Store(cfg)
+}
 
-// ---------- Substitute Environment Variables in Auth Fields ----------
-func substituteEnvVars(val string) string {
-	re := regexp.MustCompile(`^\$\{(\w+)\}$`)
-	if m := re.FindStringSubmatch(val); len(m) == 2 {
-		if v := os.Getenv(m[1]); v != "" {
-			return v
+// ---------- Load Config ----------
+
+// ParseConfig reads clouds.yml (or merges a config directory) strictly, applies flag
+// and environment overrides and defaults, and validates the result without contacting
+// the cloud (no secrets are resolved, no projects listed). An empty path uses
+// overrides and defaults only.
+func ParseConfig(path string, overrides *Overrides) (*Config, error) {
+	var data []byte
+	locate := locator(func(string) string { return "" })
+	if path != "" {
+		var err error
+		if data, locate, err = readConfigSource(path); err != nil {
+			return nil, err
 		}
 	}
-	// Optionally: fallback for "OS_VARNAME"
-	if strings.HasPrefix(val, "OS_") && strings.ToUpper(val) == val {
-		if v := os.Getenv(val); v != "" {
-			return v
+	name := configName(path)
+	// Type errors and unknown keys leave the affected fields at their defaults, so the
+	// range checks still run and one run reports every problem
+	cfg, decodeErr := decodeStrict(data, locate)
+	if cfg == nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", name, decodeErr)
+	}
+	overridden, err := overrides.apply(cfg)
+	if err != nil {
+		err = fmt.Errorf("invalid config overrides:\n%w", err)
+		if decodeErr != nil {
+			err = errors.Join(fmt.Errorf("invalid config %s:\n%w", name, decodeErr), err)
 		}
+		return nil, err
 	}
-	return val
-}
-
-func resolveAuthEnv(auth *CloudAuth) {
-	auth.DomainID = substituteEnvVars(auth.DomainID)
-	auth.DomainName = substituteEnvVars(auth.DomainName)
-	auth.AccessKey = substituteEnvVars(auth.AccessKey)
-	auth.SecretKey = substituteEnvVars(auth.SecretKey)
+	errs := []error{decodeErr, locateErrors(cfg.Global.validate(), locate, overridden)}
+	if err := cfg.Global.compile(); err != nil {
+		errs = append(errs, fmt.Errorf("global.%w", err))
+	}
+	errs = append(errs, locateErrors(cfg.validateAccounts(), locate, overridden))
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", name, err)
+	}
+	return cfg, nil
 }
 
-// ---------- Load Config ----------
-func LoadConfig(path string) (*Config, error) {
-	data, err := ioutil.ReadFile(path)
+// LoadConfig parses clouds.yml, resolves secrets and resolves the projects of every account.
+func LoadConfig(path string, overrides *Overrides) (*Config, error) {
+	cfg, err := ParseConfig(path, overrides)
 	if err != nil {
 		return nil, err
 	}
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if sources := overrides.Sources(); len(sources) > 0 {
+		logs.Infof("Config settings from flags/environment: %s", strings.Join(sources, ", "))
+	}
+	if path != "" {
+		logs.Infof("✅ Loaded config from %s", path)
+	}
+	// The transport is built first, since the secret store is reached through it;
+	// the proxy credentials are read per request, once resolved below
+	if cfg.Global.transport, err = cfg.Global.newTransport(); err != nil {
+		return nil, err
+	}
+	resolver, err := newSecretResolver(&cfg.Global)
+	if err != nil {
+		return nil, &ResolveError{Err: err}
+	}
+	if err := resolveGlobalSecrets(&cfg.Global, resolver); err != nil {
+		return nil, &ResolveError{Err: err}
+	}
+	if err := normalizeAccounts(cfg); err != nil {
 		return nil, err
 	}
-	logs.Infof("✅ Loaded config from %s", path)
-	// Substitute env vars in Auth fields if present
-	resolveAuthEnv(&cfg.Auth)
+	if cfg.Global.IgnoreSSLVerify {
+		logs.Warnf("⚠️ ignore_ssl_verify is enabled: TLS certificates of cloud APIs and proxies are NOT verified, credentials can be intercepted. Set http_client.tls.ca_bundle instead")
+	}
+	// Resolve each account in isolation so one failing account does not stop the others
+	var accounts []AccountConfig
+	for _, account := range cfg.Accounts {
+		account.global = &cfg.Global
+		if err := resolveAccount(&account, &cfg.Global.ProjectDiscovery.PatternFilter, resolver); err != nil {
+			logs.Errorf("❌ Skipping account %s: %v", account.Name, err)
+			cfg.skipped = append(cfg.skipped, fmt.Errorf("account %s: %w", account.Name, err))
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	if len(accounts) == 0 {
+		return nil, &ResolveError{Err: fmt.Errorf("no account could be resolved:\n%w", cfg.SkippedAccounts())}
+	}
+	cfg.Accounts = accounts
+	return cfg, nil
+}
+
+// ---------- Accounts ----------
+
+// normalizeAccounts turns the legacy auth block into an account and validates names.
+func normalizeAccounts(cfg *Config) error {
+	if len(cfg.Accounts) == 0 {
+		cfg.Accounts = []AccountConfig{{Name: constants.DefaultAccountName, CloudAuth: cfg.Auth}}
+	}
+	seen := make(map[string]bool, len(cfg.Accounts))
+	for i := range cfg.Accounts {
+		if cfg.Accounts[i].Name == "" {
+			return fmt.Errorf("accounts[%d]: name is required", i)
+		}
+		if seen[cfg.Accounts[i].Name] {
+			return fmt.Errorf("accounts[%d]: duplicate account name %q", i, cfg.Accounts[i].Name)
+		}
+		seen[cfg.Accounts[i].Name] = true
+	}
+	return nil
+}
+
+// ResolveError is a failure to read secrets or resolve projects, e.g. because a secret
+// store or IAM is unreachable. Unlike invalid configuration, retrying may succeed.
+type ResolveError struct {
+	Err error
+}
+
+func (e *ResolveError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// SkippedAccounts returns why accounts were left out because their secrets or
+// projects could not be resolved, or nil when every account was loaded.
+func (c *Config) SkippedAccounts() error {
+	return errors.Join(c.skipped...)
+}
+
+// AccountCount returns the number of accounts, counting the legacy auth block as one.
+func (c *Config) AccountCount() int {
+	if len(c.Accounts) == 0 {
+		return 1
+	}
+	return len(c.Accounts)
+}
+
+// resolveAccount resolves secrets and project IDs for every region of an account.
+func resolveAccount(account *AccountConfig, filter *PatternFilter, resolver *secrets.Resolver) error {
+	// Resolve credentials through the provider chain (env, files, secret store)
+	if err := resolveAuthSecrets(account.Name, &account.CloudAuth, resolver); err != nil {
+		return err
+	}
+	normalizeRegions(&account.CloudAuth)
+	if err := compileProjectSelectors(&account.CloudAuth); err != nil {
+		return err
+	}
+	if err := initCredentials(account.Name, &account.CloudAuth); err != nil {
+		return err
+	}
 	// Fill project IDs if missing
-	if err := resolveProjectIDs(&cfg.Auth); err != nil {
-		return nil, fmt.Errorf("resolving project IDs failed: %w", err)
+	for i := range account.Regions {
+		if err := resolveProjectIDs(&account.CloudAuth, &account.Regions[i], filter); err != nil {
+			return fmt.Errorf("resolving project IDs for region %s failed: %w", account.Regions[i].Name, err)
+		}
 	}
-	AppConfig = &cfg
-	return AppConfig, nil
+	return nil
+}
+
+// ForAccount returns a copy of the config whose Auth is the given account.
+func (c *Config) ForAccount(account AccountConfig) *Config {
+	acctCfg := *c
+	acctCfg.Auth = account.CloudAuth
+	return &acctCfg
+}
+
+// ---------- Regions ----------
+
+// normalizeRegions turns the legacy single-region settings into a Regions entry.
+func normalizeRegions(auth *CloudAuth) {
+	if len(auth.Regions) > 0 || auth.Region == "" {
+		return
+	}
+	auth.Regions = []RegionConfig{{
+		Name:     auth.Region,
+		AuthURL:  auth.AuthURL,
+		Projects: auth.Projects,
+	}}
+}
+
+// ForRegion returns a copy of the auth settings scoped to a single region.
+func (a CloudAuth) ForRegion(region RegionConfig) CloudAuth {
+	a.Region = region.Name
+	a.AuthURL = region.AuthURL
+	a.Projects = region.Projects
+	a.Regions = nil
+	return a
 }
 
 // ---------- Resolve Project IDs ----------
-func resolveProjectIDs(auth *CloudAuth) error {
-	allProjects, err := FetchAllProjects(*auth)
+func resolveProjectIDs(auth *CloudAuth, region *RegionConfig, filter *PatternFilter) error {
+	allProjects, err := FetchAllProjects(auth.ForRegion(*region))
 	if err != nil {
 		return err
 	}
-	regionPrefix := auth.Region + "_" // e.g. "eu-de_"
-	projectMap := make(map[string]string)
 	// Log the fetched projects for debugging
-	logs.Infof("Fetched projects: %v", allProjects)
-	// Filter and map only matching projects
-	for _, p := range allProjects {
-		if p.Name == auth.Region || strings.HasPrefix(p.Name, regionPrefix) {
-			projectMap[p.Name] = p.ID
+	logs.Debugf("Fetched projects: %v", allProjects)
+	region.discoverAll = len(region.Projects) == 0
+	region.configured = region.Projects
+	region.Projects = nil
+	for _, m := range selectProjects(*region, allProjects, filter) {
+		if !m.selected {
+			logs.Debugf("ℹ️ Project %s not selected for region %s: %s", m.Name, region.Name, m.reason)
+			continue
 		}
+		logs.Infof("✅ Project %s (%s) selected for region %s: %s", m.Name, m.ID, region.Name, m.reason)
+		region.Projects = append(region.Projects, m.ProjectConfig)
 	}
-	// If no projects specified, use all matching the region
-	if len(auth.Projects) == 0 {
-		for name, id := range projectMap {
-			auth.Projects = append(auth.Projects, ProjectConfig{Name: name, ID: id})
-		}
-	} else {
-		// Fill missing IDs
-		for i, proj := range auth.Projects {
-			if proj.ID == "" {
-				if id, ok := projectMap[proj.Name]; ok {
-					auth.Projects[i].ID = id
-					logs.Infof("ℹ️ Resolved project %s to ID %s", proj.Name, id)
-				} else {
-					// Log error but continue with other projects
-					logs.Warnf("⚠️ Project %s not found for region %s, skipping.", proj.Name, auth.Region)
-				}
-			}
-		}
+	if len(region.Projects) == 0 {
+		logs.Warnf("⚠️ No project selected for region %s", region.Name)
 	}
 	return nil
 }
 
-// ---------- Fetch All Projects ----------
-func FetchAllProjects(auth CloudAuth) ([]ProjectInfo, error) {
-	creds, err := global.NewCredentialsBuilder().
-		WithAk(auth.AccessKey).
-		WithSk(auth.SecretKey).
-		WithDomainId(auth.DomainID).
-		SafeBuild()
-	if err != nil {
-		return nil, fmt.Errorf("failed to build credentials: %w", err)
+// newIamClient builds an IAM client for the auth's region using global (domain) credentials.
+func newIamClient(auth CloudAuth) (*iam.IamClient, error) {
+	src := auth.Credentials()
+	if creds := src.Get(); creds.AccessKey == "" || creds.SecretKey == "" {
+		return nil, fmt.Errorf("failed to build credentials: access key and secret key are required")
 	}
 	iamEndpoint := auth.AuthURL
 	if iamEndpoint == "" {
-		iamEndpoint = fmt.Sprintf("https://iam.%s.otc.t-systems.com", auth.Region)
+		iamEndpoint = CatalogEndpoint(auth.Region, constants.ServiceIAM)
 	}
-	hc, _ := iam.IamClientBuilder().
+	hc, err := iam.IamClientBuilder().
 		WithEndpoints([]string{iamEndpoint}).
-		WithCredential(creds).
+		WithCredential(src.GlobalSnapshot(auth.DomainID)).
+		WithHttpConfig(auth.httpConfig()).
 		SafeBuild()
-	client := iam.NewIamClient(hc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build IAM client: %w", err)
+	}
+	return iam.NewIamClient(hc.WithCredential(src.Global(auth.DomainID))), nil
+}
+
+// ---------- Fetch All Projects ----------
+func FetchAllProjects(auth CloudAuth) ([]ProjectInfo, error) {
+	client, err := newIamClient(auth)
+	if err != nil {
+		return nil, err
+	}
 	req := &model.KeystoneListProjectsRequest{}
 	resp, err := client.KeystoneListProjects(req)
 	if err != nil {
@@ -185,8 +427,9 @@ func FetchAllProjects(auth CloudAuth) ([]ProjectInfo, error) {
 	for _, proj := range *resp.Projects {
 		if proj.Name != "" && proj.Id != "" {
 			result = append(result, ProjectInfo{
-				Name: proj.Name,
-				ID:   proj.Id,
+				Name:        proj.Name,
+				ID:          proj.Id,
+				Description: proj.Description,
 			})
 		}
 	}
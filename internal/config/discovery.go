@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
+)
+
+// keystoneServiceTypes maps Keystone service types to the exporter's service keys.
+var keystoneServiceTypes = map[string][]string{
+	"ces":      {constants.ServiceCES},
+	"rms":      {constants.ServiceRMS},
+	"evs":      {constants.NamespaceEVS},
+	"volumev2": {constants.NamespaceEVS},
+	"object":   {constants.NamespaceOBS},
+	"obs":      {constants.NamespaceOBS},
+	"compute":  {constants.NamespaceECS, constants.NamespaceAGT},
+	"ecs":      {constants.NamespaceECS, constants.NamespaceAGT},
+}
+
+// DiscoverEndpoints reads the Keystone service catalog and returns the public
+// endpoint of each known service, keyed by region and service key.
+func DiscoverEndpoints(auth CloudAuth) (map[string]map[string]string, error) {
+	client, err := newIamClient(auth)
+	if err != nil {
+		return nil, err
+	}
+	servicesResp, err := client.KeystoneListServices(&model.KeystoneListServicesRequest{})
+	if err != nil {
+		return nil, catalogError("list services", err)
+	}
+	serviceKeys := make(map[string][]string)
+	if servicesResp.Services != nil {
+		for _, svc := range *servicesResp.Services {
+			if keys, ok := keystoneServiceTypes[svc.Type]; ok && svc.Enabled {
+				serviceKeys[svc.Id] = keys
+			}
+		}
+	}
+	iface := model.GetKeystoneListEndpointsRequestInterfaceEnum().PUBLIC
+	endpointsResp, err := client.KeystoneListEndpoints(&model.KeystoneListEndpointsRequest{Interface: &iface})
+	if err != nil {
+		return nil, catalogError("list endpoints", err)
+	}
+	discovered := make(map[string]map[string]string)
+	if endpointsResp.Endpoints != nil {
+		for _, ep := range *endpointsResp.Endpoints {
+			keys, ok := serviceKeys[ep.ServiceId]
+			if !ok || !ep.Enabled {
+				continue
+			}
+			base, err := endpointBaseURL(ep.Url)
+			if err != nil {
+				logs.Debugf("Ignoring catalog endpoint %q: %v", ep.Url, err)
+				continue
+			}
+			region := ep.Region
+			if region == "" {
+				region = ep.RegionId
+			}
+			if discovered[region] == nil {
+				discovered[region] = make(map[string]string)
+			}
+			for _, key := range keys {
+				discovered[region][key] = base
+			}
+		}
+	}
+	return discovered, nil
+}
+
+// endpointBaseURL strips the path (e.g. "/v2/$(tenant_id)s") from a catalog URL,
+// so the endpoint is usable for every project of the region.
+func endpointBaseURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("not an absolute URL")
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+func catalogError(op string, err error) error {
+	if se, ok := err.(*sdkerr.ServiceResponseError); ok {
+		return fmt.Errorf("IAM catalog %s: %s", op, se.ErrorMessage)
+	}
+	return fmt.Errorf("IAM catalog %s: %w", op, err)
+}
+
+// AddDiscovered merges discovered endpoints into the config; existing entries win.
+func (e *EndpointConfig) AddDiscovered(discovered map[string]map[string]string) {
+	if e.Discovered == nil {
+		e.Discovered = make(map[string]map[string]string)
+	}
+	for region, services := range discovered {
+		if e.Discovered[region] == nil {
+			e.Discovered[region] = make(map[string]string)
+		}
+		for key, endpoint := range services {
+			if _, exists := e.Discovered[region][key]; !exists {
+				e.Discovered[region][key] = endpoint
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/secrets"
+	"gopkg.in/yaml.v2"
+)
+
+// redactedValue replaces inline secrets in the effective config.
+const redactedValue = "<redacted>"
+
+// redact hides an inline secret; references ("env:...", "file:...", "secretstore:...") are kept.
+func redact(value string) string {
+	if value == "" || secrets.IsReference(value) {
+		return value
+	}
+	return redactedValue
+}
+
+func (a CloudAuth) redacted() CloudAuth {
+	a.AccessKey = redact(a.AccessKey)
+	a.SecretKey = redact(a.SecretKey)
+	a.SecurityToken = redact(a.SecurityToken)
+	return a
+}
+
+// EffectiveYAML renders the config after merging, overrides and defaults, with secrets redacted.
+func (c *Config) EffectiveYAML() ([]byte, error) {
+	out := *c
+	out.Auth = c.Auth.redacted()
+	out.Accounts = make([]AccountConfig, len(c.Accounts))
+	for i, account := range c.Accounts {
+		account.CloudAuth = account.CloudAuth.redacted()
+		out.Accounts[i] = account
+	}
+	out.Global.Password = redact(c.Global.Password)
+	if c.Global.SecretStore != nil {
+		store := *c.Global.SecretStore
+		store.Token = redact(store.Token)
+		out.Global.SecretStore = &store
+	}
+	return yaml.Marshal(&out)
+}
@@ -1,41 +1,90 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
 	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
 	"os"
 	"strings"
+	"sync"
 )
 
+// EndpointConfig holds endpoint overrides on top of the built-in catalog.
 type EndpointConfig struct {
 	Region   string            `yaml:"region"`
 	Services map[string]string `yaml:"services"`
+	// Discovered holds endpoints read from the IAM service catalog, keyed by region
+	Discovered map[string]map[string]string `yaml:"-"`
 }
 
-// LoadEndpointConfig loads and parses endpoints.yaml
-func LoadEndpointConfig(path string) (*EndpointConfig, error) {
+// LoadEndpointConfig loads and parses the optional endpoints.yml override file.
+// A missing file is only an error when required is true.
+func LoadEndpointConfig(path string, required bool) (*EndpointConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !required {
+			logs.Infof("No endpoint override file at %s; using built-in endpoint catalog", path)
+			return &EndpointConfig{Services: map[string]string{}}, nil
+		}
 		logs.Errorf("Failed to read endpoint config %s: %v", path, err)
 		return nil, err
 	}
 
 	var cfg EndpointConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
 		logs.Errorf("Failed to parse endpoint config %s: %v", path, err)
 		return nil, err
 	}
-
+	if cfg.Services == nil {
+		cfg.Services = map[string]string{}
+	}
+	logs.Infof("✅ Loaded %d endpoint overrides from %s", len(cfg.Services), path)
 	return &cfg, nil
 }
 
+// warnedEndpoints holds the endpoint warnings already logged; ForRegion runs on every
+// reload and /debug/endpoints request, so each warning is logged once per process.
+var warnedEndpoints sync.Map
+
+func warnEndpointOnce(key, format string, args ...interface{}) {
+	if _, logged := warnedEndpoints.LoadOrStore(key, true); !logged {
+		logs.Warnf(format, args...)
+	}
+}
+
 // GetServiceEndpoint returns the full endpoint URL with region filled in
 func (e *EndpointConfig) GetServiceEndpoint(service string) (string, error) {
 	tpl, ok := e.Services[service]
 	if !ok {
-		logs.Warnf("Service %q not found in endpoint config for region %s", service, e.Region)
+		warnEndpointOnce(e.Region+"/"+service, "Service %q not found in endpoint config for region %s", service, e.Region)
 		return "", fmt.Errorf("service %q not found in endpoint config", service)
 	}
 	return strings.ReplaceAll(tpl, "{region}", e.Region), nil
 }
+
+// ForRegion resolves all service endpoints for a region: the built-in catalog,
+// overlaid by discovered endpoints, endpoints.yml and then per-region overrides.
+func (e *EndpointConfig) ForRegion(region string, overrides map[string]string) *EndpointConfig {
+	services := CatalogEndpoints(region)
+	if !IsKnownRegion(region) {
+		warnEndpointOnce(region, "Region %s is not in the built-in endpoint catalog; assuming *.%s.%s endpoints", region, region, constants.DomainOTC)
+	}
+	domain := regionDomain(region)
+	for key, endpoint := range e.Discovered[region] {
+		services[key] = endpoint
+	}
+	for key, tpl := range e.Services {
+		services[key] = expandEndpoint(tpl, region, domain)
+	}
+	for key, tpl := range overrides {
+		services[key] = expandEndpoint(tpl, region, domain)
+	}
+	return &EndpointConfig{Region: region, Services: services}
+}
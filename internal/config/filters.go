@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatternFilter is an include/exclude pair of exact names, globs or "re:" regexes.
+type PatternFilter struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	include *PatternList
+	exclude *PatternList
+}
+
+// Compile validates and compiles the include/exclude patterns.
+func (f *PatternFilter) Compile() error {
+	var err error
+	if f.include, err = NewPatternList(f.Include); err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+	if f.exclude, err = NewPatternList(f.Exclude); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	return nil
+}
+
+// Allows reports whether a value passes the filter.
+func (f *PatternFilter) Allows(value string) bool {
+	if f == nil {
+		return true
+	}
+	if !f.include.Empty() && !f.include.Match(value) {
+		return false
+	}
+	return !f.exclude.Match(value)
+}
+
+// Explain is like Allows but also describes which pattern decided the outcome.
+func (f *PatternFilter) Explain(value string) (string, bool) {
+	if f == nil {
+		return "", true
+	}
+	if p, ok := f.exclude.MatchingPattern(value); ok {
+		return fmt.Sprintf("excluded by %q", p), false
+	}
+	if f.include.Empty() {
+		return "", true
+	}
+	if p, ok := f.include.MatchingPattern(value); ok {
+		return fmt.Sprintf("matches %q", p), true
+	}
+	return "matches no include pattern", false
+}
+
+// HasInclude reports whether the filter restricts values to an allowlist.
+func (f *PatternFilter) HasInclude() bool {
+	return f != nil && len(f.Include) > 0
+}
+
+// ResourceFilter scopes collection to specific resources of a namespace.
+// Dimension and resource ID filters work on Cloud Eye dimensions directly;
+// name and tag filters are evaluated against the RMS inventory.
+type ResourceFilter struct {
+	Dimensions    map[string]*PatternFilter `yaml:"dimensions,omitempty"`
+	ResourceIDs   *PatternFilter            `yaml:"resource_ids,omitempty"`
+	ResourceNames *PatternFilter            `yaml:"resource_names,omitempty"`
+	Tags          map[string]*PatternFilter `yaml:"tags,omitempty"`
+}
+
+// Compile validates and compiles all patterns of the filter. Dimension names are
+// lowercased, since they are matched against lowercased Cloud Eye dimension names.
+func (f *ResourceFilter) Compile() error {
+	dimensions := make(map[string]*PatternFilter, len(f.Dimensions))
+	for dim, pf := range f.Dimensions {
+		key := strings.ToLower(dim)
+		if _, dup := dimensions[key]; dup {
+			return fmt.Errorf("dimensions.%s: is set more than once (dimension names are case-insensitive)", dim)
+		}
+		dimensions[key] = pf
+		if pf == nil {
+			continue
+		}
+		if err := pf.Compile(); err != nil {
+			return fmt.Errorf("dimensions.%s.%w", dim, err)
+		}
+	}
+	if f.Dimensions != nil {
+		f.Dimensions = dimensions
+	}
+	if f.ResourceIDs != nil {
+		if err := f.ResourceIDs.Compile(); err != nil {
+			return fmt.Errorf("resource_ids.%w", err)
+		}
+	}
+	if f.ResourceNames != nil {
+		if err := f.ResourceNames.Compile(); err != nil {
+			return fmt.Errorf("resource_names.%w", err)
+		}
+	}
+	for tag, pf := range f.Tags {
+		if pf == nil {
+			continue
+		}
+		if err := pf.Compile(); err != nil {
+			return fmt.Errorf("tags.%s.%w", tag, err)
+		}
+	}
+	return nil
+}
+
+// NeedsInventory reports whether the filter requires RMS resource attributes.
+func (f *ResourceFilter) NeedsInventory() bool {
+	return f.ResourceNames != nil || len(f.Tags) > 0
+}
+
+// RequiresInventoryMatch reports whether resources missing from RMS must be dropped,
+// which is the case when name or tag filters define an allowlist.
+func (f *ResourceFilter) RequiresInventoryMatch() bool {
+	if f.ResourceNames.HasInclude() {
+		return true
+	}
+	for _, pf := range f.Tags {
+		if pf.HasInclude() {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsResource evaluates name and tag filters against an RMS resource.
+func (f *ResourceFilter) AllowsResource(resource map[string]string) bool {
+	if !f.ResourceNames.Allows(resource["name"]) {
+		return false
+	}
+	for key, pf := range f.Tags {
+		if !pf.Allows(resource["tag_"+key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compile validates and compiles all pattern-based settings of the global section.
+func (g *Global) compile() error {
+	if err := g.TagPolicy.Compile(); err != nil {
+		return err
+	}
+	if err := g.ProjectDiscovery.Compile(); err != nil {
+		return fmt.Errorf("project_discovery.%w", err)
+	}
+	if err := g.NamespaceDiscovery.Compile(); err != nil {
+		return fmt.Errorf("namespace_discovery.%w", err)
+	}
+	for ns, f := range g.MetricFilters {
+		if f == nil {
+			continue
+		}
+		if err := f.Compile(); err != nil {
+			return fmt.Errorf("metric_filters.%s.%w", ns, err)
+		}
+	}
+	for ns, f := range g.ResourceFilters {
+		if f == nil {
+			continue
+		}
+		if err := f.Compile(); err != nil {
+			return fmt.Errorf("resource_filters.%s.%w", ns, err)
+		}
+	}
+	for ns, c := range g.CustomNamespaces {
+		if c == nil {
+			g.CustomNamespaces[ns] = &CustomNamespace{}
+			continue
+		}
+		if err := c.validate(ns); err != nil {
+			return fmt.Errorf("custom_namespaces.%s: %w", ns, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// BaseConfigFile is merged first when -config points to a directory.
+const BaseConfigFile = "clouds.yml"
+
+// replaceTag on a list or mapping in a fragment replaces the merged value instead of merging into it.
+const replaceTag = "!replace"
+
+// locator returns where a YAML path was set ("line 12" or "conf.d/20-team.yml line 5"), or "".
+type locator func(path string) string
+
+// SourceFiles lists the files a config path consists of: the file itself, or for
+// a directory its base clouds.yml followed by every other *.yml/*.yaml in lexical order.
+func SourceFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var base string
+	var fragments []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		if e.Name() == BaseConfigFile {
+			base = filepath.Join(path, e.Name())
+			continue
+		}
+		fragments = append(fragments, filepath.Join(path, e.Name()))
+	}
+	sort.Strings(fragments)
+	if base != "" {
+		fragments = append([]string{base}, fragments...)
+	}
+	if len(fragments) == 0 {
+		return nil, fmt.Errorf("config directory %s contains no *.yml or *.yaml files", path)
+	}
+	return fragments, nil
+}
+
+// readConfigSource reads a config file, or deep-merges the files of a config directory.
+func readConfigSource(path string) ([]byte, locator, error) {
+	files, err := SourceFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, _ := os.Stat(path)
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		lines := yamlPathLines(data)
+		return data, func(p string) string {
+			if line, ok := lines[p]; ok {
+				return fmt.Sprintf("line %d", line)
+			}
+			return ""
+		}, nil
+	}
+	origins := make(map[string]string)
+	var merged *yamlv3.Node
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		var doc yamlv3.Node
+		if err := yamlv3.Unmarshal(data, &doc); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			continue // empty fragment
+		}
+		root := doc.Content[0]
+		if root.Kind != yamlv3.MappingNode {
+			return nil, nil, fmt.Errorf("%s: top level must be a mapping", file)
+		}
+		m := &merger{file: file, origins: origins}
+		if merged == nil {
+			merged = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+		}
+		m.mergeMapping(merged, root, "")
+	}
+	if merged == nil {
+		return nil, func(string) string { return "" }, nil
+	}
+	stripReplaceTags(merged)
+	data, err := yamlv3.Marshal(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding merged config: %w", err)
+	}
+	return data, func(p string) string { return origins[p] }, nil
+}
+
+// merger deep-merges one fragment into the merged document and records where each path was set.
+//
+//   - mappings are merged key by key
+//   - scalars of later fragments win
+//   - lists whose items all have a "name" (accounts, regions, projects) are merged
+//     by name; unknown names are appended
+//   - other lists are appended, skipping scalars that are already present
+//   - a list or mapping tagged !replace replaces the merged value
+type merger struct {
+	file    string
+	origins map[string]string
+}
+
+func (m *merger) record(path string, n *yamlv3.Node) {
+	m.origins[path] = fmt.Sprintf("%s line %d", m.file, n.Line)
+}
+
+// recordAll records the origin of every key below n, e.g. after a value was copied or replaced.
+func (m *merger) recordAll(n *yamlv3.Node, path string) {
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			child := joinPath(path, n.Content[i].Value)
+			m.record(child, n.Content[i])
+			m.recordAll(n.Content[i+1], child)
+		}
+	case yamlv3.SequenceNode:
+		for i, c := range n.Content {
+			m.recordAll(c, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (m *merger) mergeMapping(dst, src *yamlv3.Node, path string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		child := joinPath(path, key.Value)
+		m.record(child, key)
+		existing := mappingValue(dst, key.Value)
+		if existing == nil {
+			dst.Content = append(dst.Content, key, value)
+			m.recordAll(value, child)
+			continue
+		}
+		m.mergeValue(existing, value, child)
+	}
+}
+
+// mergeValue merges src into dst in place.
+func (m *merger) mergeValue(dst, src *yamlv3.Node, path string) {
+	switch {
+	case src.Tag == replaceTag || src.Kind != dst.Kind || src.Kind == yamlv3.ScalarNode || src.Kind == yamlv3.AliasNode:
+		*dst = *src
+		m.recordAll(src, path)
+	case src.Kind == yamlv3.MappingNode:
+		m.mergeMapping(dst, src, path)
+	case src.Kind == yamlv3.SequenceNode:
+		m.mergeSequence(dst, src, path)
+	}
+}
+
+func (m *merger) mergeSequence(dst, src *yamlv3.Node, path string) {
+	byName := namedItems(dst) && namedItems(src)
+	for _, item := range src.Content {
+		if byName {
+			if i := indexByName(dst, mappingValue(item, "name").Value); i >= 0 {
+				m.mergeValue(dst.Content[i], item, fmt.Sprintf("%s[%d]", path, i))
+				continue
+			}
+		} else if item.Kind == yamlv3.ScalarNode && containsScalar(dst, item.Value) {
+			continue
+		}
+		dst.Content = append(dst.Content, item)
+		m.recordAll(item, fmt.Sprintf("%s[%d]", path, len(dst.Content)-1))
+	}
+}
+
+// namedItems reports whether every item of a list is a mapping with a scalar "name".
+func namedItems(n *yamlv3.Node) bool {
+	for _, item := range n.Content {
+		if item.Kind != yamlv3.MappingNode {
+			return false
+		}
+		if name := mappingValue(item, "name"); name == nil || name.Kind != yamlv3.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+func indexByName(n *yamlv3.Node, name string) int {
+	for i, item := range n.Content {
+		if v := mappingValue(item, "name"); v != nil && v.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsScalar(n *yamlv3.Node, value string) bool {
+	for _, item := range n.Content {
+		if item.Kind == yamlv3.ScalarNode && item.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func mappingValue(n *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// stripReplaceTags drops the !replace markers so the merged document decodes normally.
+func stripReplaceTags(n *yamlv3.Node) {
+	if n.Tag == replaceTag {
+		n.Tag = ""
+	}
+	for _, c := range n.Content {
+		stripReplaceTags(c)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// isConfigDir reports whether a config path is a directory of fragments.
+func isConfigDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// configName describes a config path in messages.
+func configName(path string) string {
+	if path == "" {
+		return "(no config file)"
+	}
+	if isConfigDir(path) {
+		return strings.TrimSuffix(path, "/") + "/"
+	}
+	return path
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// writeFragments writes files into a new config directory and returns its path.
+func writeFragments(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadConfigSourceMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "mappings merge and later scalars win",
+			files: map[string]string{
+				"clouds.yml": "global:\n  port: ':9098'\n  metric_path: /metrics\n",
+				"10-a.yml":   "global:\n  port: ':9100'\n  namespaces: SYS.ECS\n",
+			},
+			want: "global:\n  port: ':9100'\n  metric_path: /metrics\n  namespaces: SYS.ECS\n",
+		},
+		{
+			name: "base file first, fragments in lexical order",
+			files: map[string]string{
+				"20-b.yml":   "global:\n  namespaces: SYS.RDS\n",
+				"clouds.yml": "global:\n  namespaces: SYS.VPC\n",
+				"10-a.yaml":  "global:\n  namespaces: SYS.ECS\n",
+			},
+			want: "global:\n  namespaces: SYS.RDS\n",
+		},
+		{
+			name: "named lists merge by name and append unknown names",
+			files: map[string]string{
+				"clouds.yml": "accounts:\n  - name: a\n    region: eu-de\n  - name: b\n    region: eu-de\n",
+				"10-a.yml":   "accounts:\n  - name: b\n    region: eu-nl\n  - name: c\n    region: eu-de\n",
+			},
+			want: "accounts:\n  - name: a\n    region: eu-de\n  - name: b\n    region: eu-nl\n  - name: c\n    region: eu-de\n",
+		},
+		{
+			name: "nested named lists merge by name",
+			files: map[string]string{
+				"clouds.yml": "accounts:\n  - name: a\n    regions:\n      - name: eu-de\n        projects: [{name: p1}]\n",
+				"10-a.yml":   "accounts:\n  - name: a\n    regions:\n      - name: eu-de\n        projects: [{name: p2}]\n",
+			},
+			want: "accounts:\n  - name: a\n    regions:\n      - name: eu-de\n        projects: [{name: p1}, {name: p2}]\n",
+		},
+		{
+			name: "scalar lists append without duplicates",
+			files: map[string]string{
+				"clouds.yml": "global:\n  cce_labels:\n    namespaces: [SYS.ECS, SYS.EVS]\n",
+				"10-a.yml":   "global:\n  cce_labels:\n    namespaces: [SYS.EVS, SYS.RDS]\n",
+			},
+			want: "global:\n  cce_labels:\n    namespaces: [SYS.ECS, SYS.EVS, SYS.RDS]\n",
+		},
+		{
+			name: "replace tag on a list",
+			files: map[string]string{
+				"clouds.yml": "accounts:\n  - name: a\n  - name: b\n",
+				"10-a.yml":   "accounts: !replace\n  - name: c\n",
+			},
+			want: "accounts:\n  - name: c\n",
+		},
+		{
+			name: "replace tag on a mapping",
+			files: map[string]string{
+				"clouds.yml": "global:\n  export_rms_labels:\n    SYS.ECS: true\n    SYS.RDS: true\n",
+				"10-a.yml":   "global:\n  export_rms_labels: !replace\n    SYS.EVS: true\n",
+			},
+			want: "global:\n  export_rms_labels:\n    SYS.EVS: true\n",
+		},
+		{
+			name: "kind change replaces",
+			files: map[string]string{
+				"clouds.yml": "global:\n  namespaces: [SYS.ECS]\n",
+				"10-a.yml":   "global:\n  namespaces: SYS.RDS\n",
+			},
+			want: "global:\n  namespaces: SYS.RDS\n",
+		},
+		{
+			name: "empty fragments are skipped",
+			files: map[string]string{
+				"clouds.yml": "global:\n  port: ':9098'\n",
+				"10-a.yml":   "",
+			},
+			want: "global:\n  port: ':9098'\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _, err := readConfigSource(writeFragments(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, want interface{}
+			if err := yamlv3.Unmarshal(data, &got); err != nil {
+				t.Fatalf("merged config does not decode: %v\n%s", err, data)
+			}
+			if err := yamlv3.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("merged config:\n%s\nwant:\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadConfigSourceOrigins(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"clouds.yml": "global:\n  port: ':9098'\n  metric_path: /metrics\n",
+		"10-a.yml":   "\nglobal:\n  port: ':9100'\n",
+	})
+	_, locate, err := readConfigSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"global.port":        filepath.Join(dir, "10-a.yml") + " line 3",
+		"global.metric_path": filepath.Join(dir, "clouds.yml") + " line 3",
+		"global.namespaces":  "",
+	}
+	for path, want := range tests {
+		if got := locate(path); got != want {
+			t.Errorf("locate(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestReadConfigSourceErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"no fragments":        {"README.md": "x"},
+		"top level not a map": {"clouds.yml": "- a\n"},
+		"invalid yaml":        {"clouds.yml": "global: [\n"},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := readConfigSource(writeFragments(t, files)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// customNamespacePattern follows the CES rule for namespaces: "service.item"
+	customNamespacePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*\.[A-Za-z][A-Za-z0-9_]*$`)
+	metricPrefixPattern    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// reservedNamespacePrefixes are used by OTC services and the CES agent
+	reservedNamespacePrefixes = []string{"SYS.", "AGT.", "SRE."}
+)
+
+// NamespaceDiscovery collects every namespace in which CES has metrics for a
+// project, instead of the static namespaces list.
+type NamespaceDiscovery struct {
+	Enabled                bool `yaml:"enabled"`
+	RefreshIntervalMinutes int  `yaml:"refresh_interval_minutes"`
+	// PatternFilter restricts discovered namespaces (globs or "re:" regexes)
+	PatternFilter `yaml:",inline"`
+}
+
+// CustomNamespace configures a user-defined CES namespace that applications push metrics to.
+type CustomNamespace struct {
+	// Alias is the metric name prefix (default: lowercased last segment, "MYAPP.BILLING" -> "billing")
+	Alias string `yaml:"alias,omitempty"`
+	// ResourceDimension is the dimension used as resource_id
+	ResourceDimension string `yaml:"resource_dimension,omitempty"`
+	// ResourceNameDimension is the dimension used as resource_name
+	ResourceNameDimension string `yaml:"resource_name_dimension,omitempty"`
+	// RMSEnrichment looks up the resource in RMS (only useful if resource_id is a cloud resource)
+	RMSEnrichment bool `yaml:"rms_enrichment"`
+}
+
+func (c *CustomNamespace) validate(namespace string) error {
+	if !IsCustomNamespaceName(namespace) {
+		return fmt.Errorf("invalid namespace name %q (expected service.item, not starting with SYS., AGT. or SRE.)", namespace)
+	}
+	if c.Alias != "" && !metricPrefixPattern.MatchString(c.Alias) {
+		return fmt.Errorf("alias %q is not a valid metric name prefix", c.Alias)
+	}
+	return nil
+}
+
+// IsNamespaceName reports whether a string is syntactically a CES namespace ("service.item").
+func IsNamespaceName(namespace string) bool {
+	return customNamespacePattern.MatchString(namespace)
+}
+
+// IsCustomNamespaceName reports whether a namespace is syntactically a valid custom CES namespace.
+func IsCustomNamespaceName(namespace string) bool {
+	if !customNamespacePattern.MatchString(namespace) {
+		return false
+	}
+	for _, prefix := range reservedNamespacePrefixes {
+		if strings.HasPrefix(strings.ToUpper(namespace), prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// MetricPrefix returns the metric name prefix of a namespace: the configured
+// alias of a custom namespace, otherwise the lowercased last segment ("SYS.ECS" -> "ecs").
+func (g *Global) MetricPrefix(namespace string) string {
+	if c := g.CustomNamespaces[namespace]; c != nil && c.Alias != "" {
+		return c.Alias
+	}
+	parts := strings.Split(namespace, ".")
+	return strings.ToLower(parts[len(parts)-1])
+}
+
+// MetricPrefix returns the metric name prefix of a namespace using the active config.
+func MetricPrefix(namespace string) string {
+	cfg := Current()
+	if cfg == nil {
+		return (&Global{}).MetricPrefix(namespace)
+	}
+	return cfg.Global.MetricPrefix(namespace)
+}
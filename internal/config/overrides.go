@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config settings.
+const EnvPrefix = "OTC_EXPORTER_"
+
+// overrideField is a config setting that can be set by a flag or an environment variable.
+// Global settings use their YAML path ("metric_path", "project_discovery.enabled"),
+// auth settings are prefixed with "auth." ("auth.access_key").
+type overrideField struct {
+	key   string
+	index []int
+	kind  reflect.Type
+}
+
+// flagName is the command-line flag of the setting, e.g. -auth.access_key.
+func (f overrideField) flagName() string {
+	return f.key
+}
+
+// envName is the environment variable of the setting, e.g. OTC_EXPORTER_AUTH_ACCESS_KEY.
+func (f overrideField) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.key, ".", "_"))
+}
+
+// yamlPath is the path of the setting in clouds.yml, used in error messages.
+func (f overrideField) yamlPath() string {
+	if strings.HasPrefix(f.key, "auth.") {
+		return f.key
+	}
+	return "global." + f.key
+}
+
+var projectConfigsType = reflect.TypeOf([]ProjectConfig(nil))
+
+// overrideFields lists every scalar, string list and project list setting of Global and CloudAuth.
+// Maps and nested lists (metric_filters, relabel_configs, regions, ...) can only be set in the file.
+func overrideFields() []overrideField {
+	var fields []overrideField
+	cfgType := reflect.TypeOf(Config{})
+	global, _ := cfgType.FieldByName("Global")
+	auth, _ := cfgType.FieldByName("Auth")
+	fields = collectOverrideFields(fields, global.Type, "", global.Index)
+	fields = collectOverrideFields(fields, auth.Type, "auth.", auth.Index)
+	return fields
+}
+
+func collectOverrideFields(fields []overrideField, t reflect.Type, prefix string, index []int) []overrideField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		ft := sf.Type
+		if strings.Contains(opts, "inline") {
+			fields = collectOverrideFields(fields, ft, prefix, fieldIndex)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		if ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft == projectConfigsType:
+		case ft.Kind() == reflect.Struct:
+			fields = collectOverrideFields(fields, ft, prefix+name+".", fieldIndex)
+			continue
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.String:
+		case ft.Kind() == reflect.String, ft.Kind() == reflect.Int, ft.Kind() == reflect.Bool, ft.Kind() == reflect.Float64:
+		default:
+			continue
+		}
+		fields = append(fields, overrideField{key: prefix + name, index: fieldIndex, kind: sf.Type})
+	}
+	return fields
+}
+
+// set parses raw and stores it in the setting, allocating optional sections on the way.
+func (f overrideField) set(cfg *Config, raw string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, i := range f.index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	switch {
+	case v.Type() == projectConfigsType:
+		var projects []ProjectConfig
+		for _, name := range splitList(raw) {
+			projects = append(projects, ProjectConfig{Name: name})
+		}
+		v.Set(reflect.ValueOf(projects))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(raw)))
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Overrides holds the settings given as flags; environment variables are read when applied.
+// Precedence is flag > environment variable > config file > default.
+type Overrides struct {
+	fields []overrideField
+	flags  map[string]string
+}
+
+// overrideFlag records the value of one setting's flag.
+type overrideFlag struct {
+	overrides *Overrides
+	field     overrideField
+}
+
+func (f *overrideFlag) String() string {
+	if f.overrides == nil {
+		return ""
+	}
+	return f.overrides.flags[f.field.key]
+}
+
+func (f *overrideFlag) Set(value string) error {
+	f.overrides.flags[f.field.key] = value
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as -enable_https without a value.
+func (f *overrideFlag) IsBoolFlag() bool {
+	return f.field.kind.Kind() == reflect.Bool
+}
+
+// RegisterFlags defines a flag for every overridable setting on fs.
+func RegisterFlags(fs *flag.FlagSet) *Overrides {
+	o := &Overrides{fields: overrideFields(), flags: make(map[string]string)}
+	for _, field := range o.fields {
+		fs.Var(&overrideFlag{overrides: o, field: field}, field.flagName(),
+			fmt.Sprintf("Overrides %s (env %s)", field.yamlPath(), field.envName()))
+	}
+	return o
+}
+
+// apply sets every setting given by flag or environment variable on cfg and
+// returns the YAML paths it set. A nil Overrides applies environment variables only.
+func (o *Overrides) apply(cfg *Config) (map[string]bool, error) {
+	if o == nil {
+		o = &Overrides{}
+	}
+	fields := o.fields
+	if fields == nil {
+		fields = overrideFields()
+	}
+	applied := make(map[string]bool)
+	var errs []error
+	for _, field := range fields {
+		raw, source := "", ""
+		if value, ok := o.flags[field.key]; ok {
+			raw, source = value, "flag -"+field.flagName()
+		} else if value, ok := os.LookupEnv(field.envName()); ok {
+			raw, source = value, field.envName()
+		} else {
+			continue
+		}
+		applied[field.yamlPath()] = true
+		if err := field.set(cfg, raw); err != nil {
+			errs = append(errs, fieldErrorf(field.yamlPath(), "%s: %v", source, err))
+		}
+	}
+	return applied, errors.Join(errs...)
+}
+
+// Sources returns the settings currently given by flag or environment variable, for logging.
+func (o *Overrides) Sources() []string {
+	if o == nil {
+		o = &Overrides{}
+	}
+	fields := o.fields
+	if fields == nil {
+		fields = overrideFields()
+	}
+	var sources []string
+	for _, field := range fields {
+		if _, ok := o.flags[field.key]; ok {
+			sources = append(sources, field.yamlPath()+" (flag)")
+		} else if _, ok := os.LookupEnv(field.envName()); ok {
+			sources = append(sources, field.yamlPath()+" (env)")
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const overridesTestAuth = "auth:\n  access_key: ak\n  secret_key: sk\n  region: eu-de\n"
+
+func TestOverridesPrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags []string
+		get   func(*Config) interface{}
+		want  interface{}
+	}{
+		{
+			name: "default",
+			get:  func(c *Config) interface{} { return c.Global.MetricQueryPageLimit },
+			want: 1000,
+		},
+		{
+			name: "file over default",
+			file: "global:\n  metric_query_page_limit: 500\n",
+			get:  func(c *Config) interface{} { return c.Global.MetricQueryPageLimit },
+			want: 500,
+		},
+		{
+			name: "env over file",
+			file: "global:\n  metric_query_page_limit: 500\n",
+			env:  map[string]string{"OTC_EXPORTER_METRIC_QUERY_PAGE_LIMIT": "200"},
+			get:  func(c *Config) interface{} { return c.Global.MetricQueryPageLimit },
+			want: 200,
+		},
+		{
+			name:  "flag over env",
+			file:  "global:\n  metric_query_page_limit: 500\n",
+			env:   map[string]string{"OTC_EXPORTER_METRIC_QUERY_PAGE_LIMIT": "200"},
+			flags: []string{"-metric_query_page_limit=100"},
+			get:   func(c *Config) interface{} { return c.Global.MetricQueryPageLimit },
+			want:  100,
+		},
+		{
+			name:  "flag over default",
+			flags: []string{"-metric_path=/probe"},
+			get:   func(c *Config) interface{} { return c.Global.MetricPath },
+			want:  "/probe",
+		},
+		{
+			name:  "boolean flag without value",
+			flags: []string{"-project_discovery.enabled"},
+			get:   func(c *Config) interface{} { return c.Global.ProjectDiscovery.Enabled },
+			want:  true,
+		},
+		{
+			name: "nested setting from env",
+			env:  map[string]string{"OTC_EXPORTER_HTTP_CLIENT_TIMEOUT_SECONDS": "7"},
+			get:  func(c *Config) interface{} { return c.Global.HTTPClient.TimeoutSeconds },
+			want: 7,
+		},
+		{
+			name: "auth setting from env",
+			env:  map[string]string{"OTC_EXPORTER_AUTH_REGION": "eu-nl"},
+			get:  func(c *Config) interface{} { return c.Auth.Region },
+			want: "eu-nl",
+		},
+		{
+			name:  "project list from flag",
+			flags: []string{"-auth.projects=eu-de_a, eu-de_b"},
+			get:   func(c *Config) interface{} { return c.Auth.Projects },
+			want:  []ProjectConfig{{Name: "eu-de_a"}, {Name: "eu-de_b"}},
+		},
+		{
+			name: "string list from env",
+			env:  map[string]string{"OTC_EXPORTER_HTTP_CLIENT_NO_PROXY": "a.example.com,,10.0.0.0/8"},
+			get:  func(c *Config) interface{} { return c.Global.HTTPClient.NoProxy },
+			want: []string{"a.example.com", "10.0.0.0/8"},
+		},
+		{
+			name: "optional section is allocated",
+			env:  map[string]string{"OTC_EXPORTER_SECRET_STORE_URL": "https://vault.example.com"},
+			get:  func(c *Config) interface{} { return c.Global.SecretStore.URL },
+			want: "https://vault.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			path := filepath.Join(t.TempDir(), "clouds.yml")
+			if err := os.WriteFile(path, []byte(overridesTestAuth+tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			overrides := RegisterFlags(fs)
+			if err := fs.Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := ParseConfig(path, overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.get(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverridesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		flags []string
+		want  string
+	}{
+		{
+			name: "invalid integer from env",
+			env:  map[string]string{"OTC_EXPORTER_API_MAX_RETRIES": "many"},
+			want: `global.api_max_retries: OTC_EXPORTER_API_MAX_RETRIES: invalid integer "many"`,
+		},
+		{
+			name:  "invalid boolean from flag",
+			flags: []string{"-enable_https=maybe"},
+			want:  `global.enable_https: flag -enable_https: invalid boolean "maybe"`,
+		},
+		{
+			name:  "overridden values are range-checked without a file location",
+			flags: []string{"-metric_query_page_limit=5000"},
+			want:  "global.metric_query_page_limit: must be between 1 and 1000, got 5000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			path := filepath.Join(t.TempDir(), "clouds.yml")
+			if err := os.WriteFile(path, []byte(overridesTestAuth+"global:\n  metric_query_page_limit: 500\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			overrides := RegisterFlags(fs)
+			if err := fs.Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ParseConfig(path, overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverridesSources(t *testing.T) {
+	t.Setenv("OTC_EXPORTER_METRIC_PATH", "/probe")
+	t.Setenv("OTC_EXPORTER_PORT", ":9100")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	overrides := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-port=:9200"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"global.metric_path (env)", "global.port (flag)"}
+	if got := overrides.Sources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sources() = %v, want %v", got, want)
+	}
+}
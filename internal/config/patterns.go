@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks a pattern as a regular expression instead of a glob.
+const regexPrefix = "re:"
+
+// PatternList matches strings against a list of glob ("prod-*") or regex ("re:^prod-.*$") patterns.
+type PatternList struct {
+	raw      []string
+	compiled []*regexp.Regexp
+}
+
+// NewPatternList compiles the given glob/regex patterns.
+func NewPatternList(patterns []string) (*PatternList, error) {
+	pl := &PatternList{raw: patterns}
+	for _, p := range patterns {
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		pl.compiled = append(pl.compiled, re)
+	}
+	return pl, nil
+}
+
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(p, regexPrefix) {
+		return regexp.Compile(strings.TrimPrefix(p, regexPrefix))
+	}
+	return regexp.Compile(globToRegex(p))
+}
+
+// globToRegex converts a shell-style glob (*, ?) into an anchored regular expression.
+func globToRegex(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// Empty reports whether the list has no patterns.
+func (pl *PatternList) Empty() bool {
+	return pl == nil || len(pl.compiled) == 0
+}
+
+// Match reports whether s matches any pattern in the list.
+func (pl *PatternList) Match(s string) bool {
+	_, ok := pl.MatchingPattern(s)
+	return ok
+}
+
+// MatchingPattern returns the first pattern matching s.
+func (pl *PatternList) MatchingPattern(s string) (string, bool) {
+	if pl == nil {
+		return "", false
+	}
+	for i, re := range pl.compiled {
+		if re.MatchString(s) {
+			return pl.raw[i], true
+		}
+	}
+	return "", false
+}
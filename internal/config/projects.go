@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+)
+
+// ProjectDiscovery periodically re-lists IAM projects so new projects are
+// picked up (and deleted ones dropped) without a restart.
+type ProjectDiscovery struct {
+	Enabled                bool `yaml:"enabled"`
+	RefreshIntervalMinutes int  `yaml:"refresh_interval_minutes"`
+	// PatternFilter restricts discovered project names (globs or "re:" regexes)
+	PatternFilter `yaml:",inline"`
+}
+
+// ProjectSelector selects the projects of a region by name, IAM description
+// or tags. IAM projects have no native tags, so tags are read from
+// "key=value" pairs in the project description (e.g. "env=prod team=payments").
+type ProjectSelector struct {
+	// PatternFilter matches project names
+	PatternFilter `yaml:",inline"`
+	Description   *PatternFilter            `yaml:"description,omitempty"`
+	Tags          map[string]*PatternFilter `yaml:"tags,omitempty"`
+}
+
+// Compile validates and compiles all patterns of the selector.
+func (s *ProjectSelector) Compile() error {
+	if err := s.PatternFilter.Compile(); err != nil {
+		return err
+	}
+	if s.Description != nil {
+		if err := s.Description.Compile(); err != nil {
+			return fmt.Errorf("description.%w", err)
+		}
+	}
+	for tag, pf := range s.Tags {
+		if pf == nil {
+			continue
+		}
+		if err := pf.Compile(); err != nil {
+			return fmt.Errorf("tags.%s.%w", tag, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether a project passes the selector, along with the reasons.
+// A tag without patterns only requires the tag to be present.
+func (s *ProjectSelector) Match(p ProjectInfo) ([]string, bool) {
+	if s == nil {
+		return nil, true
+	}
+	var reasons []string
+	if r, ok := s.PatternFilter.Explain(p.Name); !ok {
+		return []string{"name " + r}, false
+	} else if r != "" {
+		reasons = append(reasons, "name "+r)
+	}
+	if r, ok := s.Description.Explain(p.Description); !ok {
+		return []string{"description " + r}, false
+	} else if r != "" {
+		reasons = append(reasons, "description "+r)
+	}
+	if len(s.Tags) == 0 {
+		return reasons, true
+	}
+	tags := parseDescriptionTags(p.Description)
+	keys := make([]string, 0, len(s.Tags))
+	for key := range s.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, found := tags[key]
+		if !found {
+			return []string{fmt.Sprintf("tag %s missing", key)}, false
+		}
+		r, ok := s.Tags[key].Explain(value)
+		if !ok {
+			return []string{fmt.Sprintf("tag %s=%s %s", key, value, r)}, false
+		}
+		if r == "" {
+			r = "present"
+		}
+		reasons = append(reasons, fmt.Sprintf("tag %s=%s %s", key, value, r))
+	}
+	return reasons, true
+}
+
+// parseDescriptionTags extracts "key=value" pairs separated by spaces, commas or semicolons.
+func parseDescriptionTags(description string) map[string]string {
+	tags := make(map[string]string)
+	fields := strings.FieldsFunc(description, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '\t' || r == '\n'
+	})
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if ok && key != "" {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+// compileProjectSelectors compiles the account's selectors; regions without
+// their own selector inherit the account-level one.
+func compileProjectSelectors(auth *CloudAuth) error {
+	if auth.ProjectSelector != nil {
+		if err := auth.ProjectSelector.Compile(); err != nil {
+			return fmt.Errorf("project_selector.%w", err)
+		}
+	}
+	for i := range auth.Regions {
+		region := &auth.Regions[i]
+		if region.ProjectSelector == nil {
+			region.ProjectSelector = auth.ProjectSelector
+			continue
+		}
+		if err := region.ProjectSelector.Compile(); err != nil {
+			return fmt.Errorf("regions[%s].project_selector.%w", region.Name, err)
+		}
+	}
+	return nil
+}
+
+// projectMatch is the selection outcome of a single project.
+type projectMatch struct {
+	ProjectConfig
+	selected bool
+	reason   string
+}
+
+// SelectProjects picks the projects of a region from the full IAM project list.
+// Explicitly configured projects are resolved by name; otherwise every project
+// named "<region>" or "<region>_*" is a candidate. The region's project selector
+// and the discovery name filter apply to both.
+func SelectProjects(region RegionConfig, all []ProjectInfo, filter *PatternFilter) []ProjectConfig {
+	var selected []ProjectConfig
+	for _, m := range selectProjects(region, all, filter) {
+		if m.selected {
+			selected = append(selected, m.ProjectConfig)
+		}
+	}
+	return selected
+}
+
+func selectProjects(region RegionConfig, all []ProjectInfo, filter *PatternFilter) []projectMatch {
+	var matches []projectMatch
+	if !region.discoverAll {
+		byName := make(map[string]ProjectInfo, len(all))
+		for _, p := range all {
+			byName[p.Name] = p
+		}
+		for _, proj := range region.explicitProjects() {
+			info, ok := byName[proj.Name]
+			if proj.ID == "" {
+				if !ok {
+					// Log error but continue with other projects
+					logs.Warnf("⚠️ Project %s not found for region %s, skipping.", proj.Name, region.Name)
+					continue
+				}
+				proj.ID = info.ID
+				logs.Debugf("ℹ️ Resolved project %s to ID %s", proj.Name, info.ID)
+			}
+			info.Name, info.ID = proj.Name, proj.ID
+			matches = append(matches, matchProject(region, info, filter, "explicitly configured"))
+		}
+		return matches
+	}
+	regionPrefix := region.Name + "_" // e.g. "eu-de_"
+	for _, p := range all {
+		if p.Name != region.Name && !strings.HasPrefix(p.Name, regionPrefix) {
+			continue
+		}
+		matches = append(matches, matchProject(region, p, filter, "belongs to region"))
+	}
+	return matches
+}
+
+// matchProject applies the region's selector and the discovery filter to a candidate project.
+func matchProject(region RegionConfig, p ProjectInfo, filter *PatternFilter, base string) projectMatch {
+	m := projectMatch{ProjectConfig: ProjectConfig{Name: p.Name, ID: p.ID}}
+	if r, ok := filter.Explain(p.Name); !ok {
+		m.reason = "discovery filter: name " + r
+		return m
+	}
+	reasons, ok := region.ProjectSelector.Match(p)
+	if !ok {
+		m.reason = strings.Join(reasons, ", ")
+		return m
+	}
+	m.selected = true
+	m.reason = strings.Join(append([]string{base}, reasons...), ", ")
+	return m
+}
+
+// explicitProjects returns the projects configured by the user, before resolution.
+func (r RegionConfig) explicitProjects() []ProjectConfig {
+	if r.configured != nil {
+		return append([]ProjectConfig(nil), r.configured...)
+	}
+	return append([]ProjectConfig(nil), r.Projects...)
+}
+
+// DiscoverProjects lists the current IAM projects and selects those of the region.
+func DiscoverProjects(auth CloudAuth, region RegionConfig, filter *PatternFilter) ([]ProjectConfig, error) {
+	allProjects, err := FetchAllProjects(auth.ForRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	return SelectProjects(region, allProjects, filter), nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/credentials"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/secrets"
+)
+
+// newSecretResolver creates the resolver for all secret references of the config.
+// The secret store is reached through the shared transport.
+func newSecretResolver(g *Global) (*secrets.Resolver, error) {
+	interval := time.Duration(g.SecretRefreshIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = constants.DefaultSecretRefreshInterval
+	}
+	store := g.SecretStore
+	if store != nil {
+		withTransport := *store
+		withTransport.Transport = g.Transport()
+		store = &withTransport
+	}
+	return secrets.NewResolver(store, interval)
+}
+
+// resolveGlobalSecrets resolves the proxy credentials and TLS file paths.
+// The proxy password is watched; the other values are read once, and the HTTPS
+// server loads tls_cert/tls_key only at startup, so changing them needs a restart.
+// tls_cert/tls_key are paths: validate rejects file: and secretstore: references for them.
+func resolveGlobalSecrets(g *Global, resolver *secrets.Resolver) error {
+	var err error
+	if g.UserName, err = resolver.Resolve(g.UserName); err != nil {
+		return fmt.Errorf("proxy_username: %w", err)
+	}
+	if g.proxyPassword, err = resolver.Watch(g.Password); err != nil {
+		return fmt.Errorf("proxy_password: %w", err)
+	}
+	if g.TLSCert, err = resolver.Resolve(g.TLSCert); err != nil {
+		return fmt.Errorf("tls_cert: %w", err)
+	}
+	if g.TLSKey, err = resolver.Resolve(g.TLSKey); err != nil {
+		return fmt.Errorf("tls_key: %w", err)
+	}
+	return nil
+}
+
+// ProxyPassword returns the current (possibly rotated) proxy password.
+func (g *Global) ProxyPassword() string {
+	if g.proxyPassword == nil {
+		return g.Password
+	}
+	return g.proxyPassword.Get()
+}
+
+// resolveAuthSecrets resolves the domain once and watches the access keys, so
+// rotated keys are used by all clients of the account without a restart.
+func resolveAuthSecrets(name string, auth *CloudAuth, resolver *secrets.Resolver) error {
+	var err error
+	if auth.DomainID, err = resolver.Resolve(auth.DomainID); err != nil {
+		return fmt.Errorf("domain_id: %w", err)
+	}
+	if auth.DomainName, err = resolver.Resolve(auth.DomainName); err != nil {
+		return fmt.Errorf("domain_name: %w", err)
+	}
+	if auth.Agency != nil {
+		if auth.Agency.DomainID, err = resolver.Resolve(auth.Agency.DomainID); err != nil {
+			return fmt.Errorf("agency.domain_id: %w", err)
+		}
+		if auth.Agency.DomainName, err = resolver.Resolve(auth.Agency.DomainName); err != nil {
+			return fmt.Errorf("agency.domain_name: %w", err)
+		}
+	}
+	// The keys are read and replaced as one unit, so a rotation never pairs a
+	// new access key with the old secret key
+	keys, err := resolver.WatchGroup(auth.AccessKey, auth.SecretKey, auth.SecurityToken)
+	if err != nil {
+		return fmt.Errorf("access keys: %w", err)
+	}
+	logs.Debugf("Credentials of account %s: access_key from %s, secret_key from %s", name, keys.Provider(0), keys.Provider(1))
+	auth.source = credentials.NewWatched(name, func() credentials.Credentials {
+		values := keys.Get()
+		return credentials.Credentials{
+			AccessKey:     values[0],
+			SecretKey:     values[1],
+			SecurityToken: values[2],
+		}
+	})
+	creds := auth.source.Get()
+	auth.AccessKey, auth.SecretKey, auth.SecurityToken = creds.AccessKey, creds.SecretKey, creds.SecurityToken
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+)
+
+// HTTPServerConfig holds the timeouts of the exporter's HTTP(S) server and how it
+// drains on SIGTERM/SIGINT. Zero values use the defaults.
+type HTTPServerConfig struct {
+	ReadHeaderTimeoutSeconds int `yaml:"read_header_timeout_seconds"`
+	ReadTimeoutSeconds       int `yaml:"read_timeout_seconds"`
+	// WriteTimeoutSeconds must exceed the longest scrape
+	WriteTimeoutSeconds int `yaml:"write_timeout_seconds"`
+	IdleTimeoutSeconds  int `yaml:"idle_timeout_seconds"`
+	// ShutdownTimeoutSeconds is how long in-flight scrapes may finish on shutdown
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"`
+	// DrainDelaySeconds keeps serving while reporting not-ready, so load balancers
+	// stop sending requests before the listeners close (default 0)
+	DrainDelaySeconds int `yaml:"drain_delay_seconds"`
+}
+
+func (s HTTPServerConfig) ReadHeaderTimeout() time.Duration {
+	return secondsOr(s.ReadHeaderTimeoutSeconds, constants.DefaultServerReadHeaderTimeout)
+}
+
+func (s HTTPServerConfig) ReadTimeout() time.Duration {
+	return secondsOr(s.ReadTimeoutSeconds, constants.DefaultServerReadTimeout)
+}
+
+func (s HTTPServerConfig) WriteTimeout() time.Duration {
+	return secondsOr(s.WriteTimeoutSeconds, constants.DefaultServerWriteTimeout)
+}
+
+func (s HTTPServerConfig) IdleTimeout() time.Duration {
+	return secondsOr(s.IdleTimeoutSeconds, constants.DefaultServerIdleTimeout)
+}
+
+func (s HTTPServerConfig) ShutdownTimeout() time.Duration {
+	return secondsOr(s.ShutdownTimeoutSeconds, constants.DefaultShutdownTimeout)
+}
+
+func (s HTTPServerConfig) DrainDelay() time.Duration {
+	return time.Duration(s.DrainDelaySeconds) * time.Second
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TagPolicy controls which resource tags become labels and how they are named.
+// It is applied to every tag source (RMS, OBS, ...).
+type TagPolicy struct {
+	Include         []string          `yaml:"include,omitempty"`
+	Exclude         []string          `yaml:"exclude,omitempty"`
+	Rename          map[string]string `yaml:"rename,omitempty"`
+	LowercaseKeys   bool              `yaml:"lowercase_keys"`
+	LowercaseValues bool              `yaml:"lowercase_values"`
+	MaxValueLength  int               `yaml:"max_value_length,omitempty"`
+
+	include *PatternList
+	exclude *PatternList
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Compile validates and compiles the include/exclude patterns.
+func (p *TagPolicy) Compile() error {
+	var err error
+	if p.include, err = NewPatternList(p.Include); err != nil {
+		return fmt.Errorf("tag_policy.include: %w", err)
+	}
+	if p.exclude, err = NewPatternList(p.Exclude); err != nil {
+		return fmt.Errorf("tag_policy.exclude: %w", err)
+	}
+	return nil
+}
+
+// Allows reports whether a tag key passes the include/exclude filters.
+func (p *TagPolicy) Allows(key string) bool {
+	if !p.include.Empty() && !p.include.Match(key) {
+		return false
+	}
+	return !p.exclude.Match(key)
+}
+
+// LabelName returns the label name for a tag key ("tag_<key>" unless renamed).
+func (p *TagPolicy) LabelName(key string) string {
+	name, ok := p.Rename[key]
+	if !ok {
+		name, ok = p.Rename["tag_"+key]
+	}
+	if !ok {
+		name = "tag_" + key
+	}
+	if p.LowercaseKeys {
+		name = strings.ToLower(name)
+	}
+	return invalidLabelChars.ReplaceAllString(name, "_")
+}
+
+// LabelValue normalizes a tag value according to the policy.
+func (p *TagPolicy) LabelValue(value string) string {
+	if p.LowercaseValues {
+		value = strings.ToLower(value)
+	}
+	if p.MaxValueLength > 0 {
+		if runes := []rune(value); len(runes) > p.MaxValueLength {
+			value = string(runes[:p.MaxValueLength])
+		}
+	}
+	return value
+}
+
+// Apply converts raw tags into labels, honouring the policy.
+func (p *TagPolicy) Apply(labels map[string]string, tags map[string]string) int {
+	added := 0
+	for k, v := range tags {
+		if v == "" || !p.Allows(k) {
+			continue
+		}
+		labels[p.LabelName(k)] = p.LabelValue(v)
+		added++
+	}
+	return added
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// HTTPClientConfig tunes the outbound HTTP transport of every cloud API client
+// (CES, RMS, EVS, OBS, IAM). Zero values use the defaults.
+type HTTPClientConfig struct {
+	// NoProxy lists hosts, domains (".example.com"), IPs and CIDRs reached without
+	// the proxy, in addition to the NO_PROXY environment variable
+	NoProxy                      []string `yaml:"no_proxy,omitempty"`
+	TimeoutSeconds               int      `yaml:"timeout_seconds"`
+	DialTimeoutSeconds           int      `yaml:"dial_timeout_seconds"`
+	KeepAliveSeconds             int      `yaml:"keep_alive_seconds"`
+	TLSHandshakeTimeoutSeconds   int      `yaml:"tls_handshake_timeout_seconds"`
+	ResponseHeaderTimeoutSeconds int      `yaml:"response_header_timeout_seconds"`
+	IdleConnTimeoutSeconds       int      `yaml:"idle_conn_timeout_seconds"`
+	MaxIdleConns                 int      `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost          int      `yaml:"max_idle_conns_per_host"`
+	DisableKeepAlives            bool     `yaml:"disable_keep_alives"`
+	// TLS verifies cloud API endpoints and proxies against extra CAs and
+	// authenticates with a client certificate
+	TLS ClientTLSConfig `yaml:"tls"`
+}
+
+// ClientTLSConfig is the TLS setup of outbound connections.
+type ClientTLSConfig struct {
+	// CABundle is a PEM file, or a directory of PEM files, added to the system CA pool
+	CABundle string `yaml:"ca_bundle,omitempty"`
+	// CertFile and KeyFile are a client certificate for mTLS (e.g. to a proxy)
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// MinVersion is "1.2" (default) or "1.3"
+	MinVersion string `yaml:"min_version,omitempty"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	if v == "" {
+		return tls.VersionTLS12, nil
+	}
+	version, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(v), "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("must be one of 1.0, 1.1, 1.2 or 1.3, got %q", v)
+	}
+	return version, nil
+}
+
+// tlsConfig builds the client TLS config, reading the CA bundle and client certificate.
+func (g *Global) tlsConfig() (*tls.Config, error) {
+	t := g.HTTPClient.TLS
+	minVersion, err := parseTLSVersion(t.MinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("min_version %w", err)
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: g.IgnoreSSLVerify, MinVersion: minVersion}
+	if t.CABundle != "" {
+		if tlsConfig.RootCAs, err = loadCABundle(t.CABundle); err != nil {
+			return nil, fmt.Errorf("ca_bundle: %w", err)
+		}
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// loadCABundle returns the system CA pool plus the certificates of a PEM file or of
+// every file in a directory.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, e := range entries {
+			if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	added := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if pool.AppendCertsFromPEM(data) {
+			added++
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("%s contains no PEM certificate", file)
+		}
+	}
+	if added == 0 {
+		return nil, fmt.Errorf("%s contains no PEM certificate", path)
+	}
+	return pool, nil
+}
+
+func secondsOr(seconds int, def time.Duration) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return def
+}
+
+func intOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// timeout is the limit of a whole request including reading the response.
+func (h HTTPClientConfig) timeout() time.Duration {
+	return secondsOr(h.TimeoutSeconds, constants.DefaultHTTPTimeout)
+}
+
+// newTransport builds the transport from the proxy, http_client and TLS settings.
+func (g *Global) newTransport() (*http.Transport, error) {
+	tlsConfig, err := g.tlsConfig()
+	if err != nil {
+		return nil, fieldErrorf("global.http_client.tls", "%v", err)
+	}
+	h := g.HTTPClient
+	dialer := &net.Dialer{
+		Timeout:   secondsOr(h.DialTimeoutSeconds, constants.DefaultHTTPDialTimeout),
+		KeepAlive: secondsOr(h.KeepAliveSeconds, constants.DefaultHTTPKeepAlive),
+	}
+	return &http.Transport{
+		Proxy:                 g.proxyFunc(),
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   secondsOr(h.TLSHandshakeTimeoutSeconds, constants.DefaultHTTPTLSHandshakeTimeout),
+		ResponseHeaderTimeout: secondsOr(h.ResponseHeaderTimeoutSeconds, constants.DefaultHTTPResponseHeaderTimeout),
+		IdleConnTimeout:       secondsOr(h.IdleConnTimeoutSeconds, constants.DefaultHTTPIdleConnTimeout),
+		MaxIdleConns:          intOr(h.MaxIdleConns, constants.DefaultHTTPMaxIdleConns),
+		MaxIdleConnsPerHost:   intOr(h.MaxIdleConnsPerHost, constants.DefaultHTTPMaxIdleConnsPerHost),
+		DisableKeepAlives:     h.DisableKeepAlives,
+	}, nil
+}
+
+// proxyFunc selects the proxy of a request. Without proxy_host, HTTPS_PROXY, HTTP_PROXY
+// and NO_PROXY from the environment apply. With proxy_host, every request goes through
+// it except hosts matching no_proxy or NO_PROXY; the (possibly rotated) proxy password
+// is read per request.
+func (g *Global) proxyFunc() func(*http.Request) (*url.URL, error) {
+	env := httpproxy.FromEnvironment()
+	if !g.isProxyConfigured() {
+		if env.HTTPSProxy != "" || env.HTTPProxy != "" {
+			logs.Debugf("Proxy not configured; using HTTPS_PROXY/HTTP_PROXY from the environment.")
+		} else {
+			logs.Debugf("Proxy not configured; using direct connection.")
+		}
+		fromEnv := env.ProxyFunc()
+		return func(req *http.Request) (*url.URL, error) {
+			return fromEnv(req.URL)
+		}
+	}
+	noProxy := g.HTTPClient.NoProxy
+	if env.NoProxy != "" {
+		noProxy = append(append([]string(nil), noProxy...), env.NoProxy)
+	}
+	// bypass reports nil for hosts that are reached directly
+	const placeholder = "http://proxy.invalid"
+	bypass := (&httpproxy.Config{
+		HTTPProxy:  placeholder,
+		HTTPSProxy: placeholder,
+		NoProxy:    strings.Join(noProxy, ","),
+	}).ProxyFunc()
+	if !g.isProxyAuthConfigured() {
+		logs.Debugf("Proxy authentication not configured; using proxy without auth.")
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if via, err := bypass(req.URL); err != nil || via == nil {
+			return nil, err
+		}
+		proxy := &url.URL{Scheme: g.HttpSchema, Host: net.JoinHostPort(g.HttpHost, strconv.Itoa(g.HttpPort))}
+		if g.isProxyAuthConfigured() {
+			proxy.User = url.UserPassword(g.UserName, g.ProxyPassword())
+		}
+		return proxy, nil
+	}
+}
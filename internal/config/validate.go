@@ -0,0 +1,321 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/registry"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/secrets"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// validMetricPeriods are the CES aggregation periods (1 = raw data).
+var validMetricPeriods = []int{1, 300, 1200, 3600, 14400, 86400}
+
+// FieldError is a configuration error at a YAML path, e.g. "global.metric_query_page_limit".
+type FieldError struct {
+	Path string
+	// Where is the file and/or line the path was set at, if known
+	Where string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	if e.Where != "" {
+		return fmt.Sprintf("%s (%s): %s", e.Path, e.Where, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+func fieldErrorf(path, format string, args ...interface{}) *FieldError {
+	return &FieldError{Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// defaultConfig returns the config that file values are decoded onto, so keys
+// that are not set keep these defaults while explicit values are range-checked.
+func defaultConfig() Config {
+	return Config{Global: Global{
+		Port:                        fmt.Sprintf("0.0.0.0:%d", constants.DefaultPort),
+		HTTPSPort:                   fmt.Sprintf("0.0.0.0:%d", constants.DefaultHTTPSPort),
+		MetricPath:                  constants.DefaultMetricPath,
+		Namespaces:                  constants.DefaultNamespaces,
+		APIMaxRetries:               constants.MaxRetries,
+		APIRetryInitialDelaySeconds: int(constants.InitialBackoff.Seconds()),
+		APIRetryMaxDelaySeconds:     int(constants.MaxBackoff.Seconds()),
+		APIRetryBackoffMultiplier:   constants.BackoffMultiplier,
+		MetricQueryPeriodMinutes:    1,
+		MetricQueryPageLimit:        int(constants.DefaultLimit),
+		MetricQueryWindowMs:         int(constants.DefaultTimeWindow.Milliseconds()),
+		MetricQueryBatchSize:        constants.MaxMetricBatchSize,
+	}}
+}
+
+// decodeStrict decodes clouds.yml onto the defaults and rejects unknown or duplicate keys.
+// For type errors and unknown keys the rest of the file is still decoded and returned
+// with the error; for syntax errors the config is nil.
+func decodeStrict(data []byte, locate locator) (*Config, error) {
+	cfg := defaultConfig()
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, annotateYAMLError(err, data, locate)
+		}
+		return &cfg, annotateYAMLError(err, data, locate)
+	}
+	return &cfg, nil
+}
+
+var (
+	yamlLineError    = regexp.MustCompile(`^line (\d+): (.*)$`)
+	yamlUnknownField = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+)
+
+// annotateYAMLError rewrites the decoder's "line N: ..." messages into FieldErrors with the YAML path.
+func annotateYAMLError(err error, data []byte, locate locator) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	paths := yamlKeyPaths(data)
+	var errs []error
+	for _, msg := range typeErr.Errors {
+		m := yamlLineError.FindStringSubmatch(msg)
+		if m == nil {
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		path, ok := paths[line]
+		if !ok {
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		detail := m[2]
+		if yamlUnknownField.MatchString(detail) {
+			detail = "unknown field"
+		}
+		errs = append(errs, &FieldError{Path: path, Where: locate(path), Msg: detail})
+	}
+	return errors.Join(errs...)
+}
+
+// yamlKeyPaths maps the line of every mapping key to its dotted path.
+func yamlKeyPaths(data []byte) map[int]string {
+	paths := make(map[int]string)
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return paths
+	}
+	var walk func(n *yamlv3.Node, path string)
+	walk = func(n *yamlv3.Node, path string) {
+		switch n.Kind {
+		case yamlv3.DocumentNode:
+			for _, c := range n.Content {
+				walk(c, path)
+			}
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key, value := n.Content[i], n.Content[i+1]
+				child := key.Value
+				if path != "" {
+					child = path + "." + key.Value
+				}
+				paths[key.Line] = child
+				walk(value, child)
+			}
+		case yamlv3.SequenceNode:
+			for i, c := range n.Content {
+				walk(c, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	walk(&root, "")
+	return paths
+}
+
+// yamlPathLines maps dotted paths back to their lines.
+func yamlPathLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	for line, path := range yamlKeyPaths(data) {
+		lines[path] = line
+	}
+	return lines
+}
+
+// locateErrors fills in where every FieldError in err was set, except for settings
+// that were overridden by a flag or environment variable.
+func locateErrors(err error, locate locator, overridden map[string]bool) error {
+	if err == nil {
+		return nil
+	}
+	var joined interface{ Unwrap() []error }
+	list := []error{err}
+	if errors.As(err, &joined) {
+		list = joined.Unwrap()
+	}
+	for _, e := range list {
+		var fe *FieldError
+		if errors.As(e, &fe) && fe.Where == "" && !overridden[fe.Path] {
+			fe.Where = locate(fe.Path)
+		}
+	}
+	return err
+}
+
+// isPathReference reports whether a file path setting is a path or an env reference
+// to one; file: and secretstore: references return contents, not a path.
+func isPathReference(ref string) bool {
+	provider := secrets.ProviderOf(ref)
+	return provider == secrets.ProviderInline || provider == secrets.ProviderEnv
+}
+
+// validate range-checks the global settings and reports every problem at once.
+func (g *Global) validate() error {
+	var errs []error
+	check := func(ok bool, path, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fieldErrorf("global."+path, format, args...))
+		}
+	}
+	checkAddr := func(path, addr string) {
+		if _, port, err := net.SplitHostPort(addr); err != nil {
+			errs = append(errs, fieldErrorf("global."+path, "must be host:port, got %q", addr))
+		} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			errs = append(errs, fieldErrorf("global."+path, "port must be between 1 and 65535, got %q", port))
+		}
+	}
+	checkAddr("port", g.Port)
+	if g.EnableHTTPS {
+		checkAddr("https_port", g.HTTPSPort)
+		check(g.TLSCert != "", "tls_cert", "is required when enable_https is true")
+		check(g.TLSKey != "", "tls_key", "is required when enable_https is true")
+	}
+	check(isPathReference(g.TLSCert), "tls_cert", "must be a path or an env reference to a path; %s references would resolve to the certificate itself", secrets.ProviderOf(g.TLSCert))
+	check(isPathReference(g.TLSKey), "tls_key", "must be a path or an env reference to a path; %s references would resolve to the key itself", secrets.ProviderOf(g.TLSKey))
+	check(strings.HasPrefix(g.MetricPath, "/"), "metric_path", "must start with /, got %q", g.MetricPath)
+	if _, err := registry.Expand(g.Namespaces); err != nil {
+		errs = append(errs, fieldErrorf("global.namespaces", "%v", err))
+	}
+
+	check(g.APIMaxRetries >= 0 && g.APIMaxRetries <= 20, "api_max_retries", "must be between 0 and 20, got %d", g.APIMaxRetries)
+	check(g.APIRetryInitialDelaySeconds >= 0, "api_retry_initial_delay_seconds", "must not be negative, got %d", g.APIRetryInitialDelaySeconds)
+	check(g.APIRetryMaxDelaySeconds >= g.APIRetryInitialDelaySeconds, "api_retry_max_delay_seconds",
+		"must be at least api_retry_initial_delay_seconds (%d), got %d", g.APIRetryInitialDelaySeconds, g.APIRetryMaxDelaySeconds)
+	check(g.APIRetryBackoffMultiplier >= 1, "api_retry_backoff_multiplier", "must be at least 1, got %v", g.APIRetryBackoffMultiplier)
+
+	check(containsInt(validMetricPeriods, g.MetricQueryPeriodMinutes), "metric_query_period_minutes",
+		"must be one of %v (1 = raw data), got %d", validMetricPeriods, g.MetricQueryPeriodMinutes)
+	check(g.MetricQueryPageLimit >= 1 && g.MetricQueryPageLimit <= int(constants.DefaultLimit), "metric_query_page_limit",
+		"must be between 1 and %d, got %d", constants.DefaultLimit, g.MetricQueryPageLimit)
+	check(g.MetricQueryWindowMs > 0, "metric_query_window_ms", "must be positive, got %d", g.MetricQueryWindowMs)
+	check(g.MetricQueryBatchSize >= 1 && g.MetricQueryBatchSize <= constants.MaxMetricBatchSize, "metric_query_batch_size",
+		"must be between 1 and %d, got %d", constants.MaxMetricBatchSize, g.MetricQueryBatchSize)
+
+	if g.HttpHost != "" {
+		if g.HttpSchema == "" {
+			g.HttpSchema = constants.DefaultProxySchema
+		}
+		if g.HttpPort == 0 {
+			g.HttpPort = constants.DefaultProxyPort
+		}
+		check(g.HttpSchema == "http" || g.HttpSchema == "https", "proxy_schema", "must be http or https, got %q", g.HttpSchema)
+		check(g.HttpPort >= 1 && g.HttpPort <= 65535, "proxy_port", "must be between 1 and 65535, got %d", g.HttpPort)
+	}
+	h, srv := g.HTTPClient, g.HTTPServer
+	for _, f := range []struct {
+		path  string
+		value int
+	}{
+		{"http_client.timeout_seconds", h.TimeoutSeconds},
+		{"http_client.dial_timeout_seconds", h.DialTimeoutSeconds},
+		{"http_client.keep_alive_seconds", h.KeepAliveSeconds},
+		{"http_client.tls_handshake_timeout_seconds", h.TLSHandshakeTimeoutSeconds},
+		{"http_client.response_header_timeout_seconds", h.ResponseHeaderTimeoutSeconds},
+		{"http_client.idle_conn_timeout_seconds", h.IdleConnTimeoutSeconds},
+		{"http_client.max_idle_conns", h.MaxIdleConns},
+		{"http_client.max_idle_conns_per_host", h.MaxIdleConnsPerHost},
+		{"http_server.read_header_timeout_seconds", srv.ReadHeaderTimeoutSeconds},
+		{"http_server.read_timeout_seconds", srv.ReadTimeoutSeconds},
+		{"http_server.write_timeout_seconds", srv.WriteTimeoutSeconds},
+		{"http_server.idle_timeout_seconds", srv.IdleTimeoutSeconds},
+		{"http_server.shutdown_timeout_seconds", srv.ShutdownTimeoutSeconds},
+		{"http_server.drain_delay_seconds", srv.DrainDelaySeconds},
+	} {
+		check(f.value >= 0, f.path, "must not be negative, got %d", f.value)
+	}
+	if _, err := g.tlsConfig(); err != nil {
+		errs = append(errs, fieldErrorf("global.http_client.tls", "%v", err))
+	}
+	check(g.SecretRefreshIntervalSeconds >= 0, "secret_refresh_interval_seconds", "must not be negative, got %d", g.SecretRefreshIntervalSeconds)
+	if g.SecretStore != nil {
+		check(g.SecretStore.URL != "", "secret_store.url", "is required")
+		check(g.SecretStore.TimeoutSeconds >= 0, "secret_store.timeout_seconds", "must not be negative, got %d", g.SecretStore.TimeoutSeconds)
+	}
+	check(g.ProjectDiscovery.RefreshIntervalMinutes >= 0, "project_discovery.refresh_interval_minutes",
+		"must not be negative, got %d", g.ProjectDiscovery.RefreshIntervalMinutes)
+	check(g.NamespaceDiscovery.RefreshIntervalMinutes >= 0, "namespace_discovery.refresh_interval_minutes",
+		"must not be negative, got %d", g.NamespaceDiscovery.RefreshIntervalMinutes)
+
+	l := g.SeriesLimits
+	check(l.MaxSeriesPerNamespace >= 0, "series_limits.max_series_per_namespace", "must not be negative, got %d", l.MaxSeriesPerNamespace)
+	check(l.MaxSeriesPerProject >= 0, "series_limits.max_series_per_project", "must not be negative, got %d", l.MaxSeriesPerProject)
+	check(l.MaxLabelValueLength >= 0, "series_limits.max_label_value_length", "must not be negative, got %d", l.MaxLabelValueLength)
+	for ns, limit := range l.Namespaces {
+		check(limit >= 0, "series_limits.namespaces."+ns, "must not be negative, got %d", limit)
+	}
+	return errors.Join(errs...)
+}
+
+// validateAccounts checks the accounts without contacting the cloud.
+func (c *Config) validateAccounts() error {
+	// Normalize a copy; LoadConfig normalizes the config itself after resolving secrets
+	normalized := *c
+	if err := normalizeAccounts(&normalized); err != nil {
+		return err
+	}
+	var errs []error
+	for i, account := range normalized.Accounts {
+		path := fmt.Sprintf("accounts[%d]", i)
+		if len(c.Accounts) == 0 {
+			path = "auth"
+		}
+		auth := account.CloudAuth
+		if auth.AccessKey == "" || auth.SecretKey == "" {
+			errs = append(errs, fieldErrorf(path, "access_key and secret_key are required"))
+		}
+		if auth.Region == "" && len(auth.Regions) == 0 {
+			errs = append(errs, fieldErrorf(path, "region or regions is required"))
+		}
+		for j, region := range auth.Regions {
+			if region.Name == "" {
+				errs = append(errs, fieldErrorf(fmt.Sprintf("%s.regions[%d].name", path, j), "is required"))
+			}
+		}
+		if auth.Agency != nil {
+			agency := *auth.Agency
+			if err := agency.validate(); err != nil {
+				errs = append(errs, fieldErrorf(path+".agency", "%v", err))
+			}
+		}
+		normalizeRegions(&auth)
+		if err := compileProjectSelectors(&auth); err != nil {
+			errs = append(errs, fieldErrorf(path, "%v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
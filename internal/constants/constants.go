@@ -7,6 +7,8 @@ const (
 	DefaultPeriod     = "1"
 	DefaultLimit      = int32(1000)
 	DefaultTimeWindow = time.Hour
+	// Maximum number of metrics per BatchListMetricData request
+	MaxMetricBatchSize = 10
 
 	// OTC Namespaces - Compute
 	NamespaceECS = "SYS.ECS"
@@ -62,16 +64,21 @@ const (
 	LabelResourceName = "resource_name"
 	LabelProjectID    = "project_id"
 	LabelProjectName  = "project_name"
+	LabelRegion       = "region"
+	LabelAccount      = "account"
 	LabelUnit         = "unit"
+	LabelCCECluster   = "cce_cluster"
+	LabelCCENodePool  = "cce_nodepool"
 
 	// Special resource IDs
 	ResourceIDTotal   = "total"
 	ResourceIDUnknown = "unknown"
 
 	// Configuration defaults
-	DefaultMetricPath = "/metrics"
-	DefaultPort       = 9098
-	DefaultHTTPSPort  = 9099
+	DefaultAccountName = "default"
+	DefaultMetricPath  = "/metrics"
+	DefaultPort        = 9098
+	DefaultHTTPSPort   = 9099
 
 	// Default namespaces
 	DefaultNamespaces = "SYS.ECS,SYS.EVS,SYS.RDS,SYS.ELB"
@@ -88,9 +95,67 @@ const (
 	DefaultProxySchema = "http"
 	DefaultProxyPort   = 8080
 
+	// Outbound HTTP transport defaults shared by all cloud API clients
+	DefaultHTTPTimeout               = 120 * time.Second
+	DefaultHTTPDialTimeout           = 10 * time.Second
+	DefaultHTTPKeepAlive             = 30 * time.Second
+	DefaultHTTPTLSHandshakeTimeout   = 10 * time.Second
+	DefaultHTTPResponseHeaderTimeout = 60 * time.Second
+	DefaultHTTPIdleConnTimeout       = 90 * time.Second
+	DefaultHTTPMaxIdleConns          = 100
+	DefaultHTTPMaxIdleConnsPerHost   = 10
+
+	// Exporter HTTP server defaults; a scrape of many projects may take minutes
+	DefaultServerReadHeaderTimeout = 10 * time.Second
+	DefaultServerReadTimeout       = 30 * time.Second
+	DefaultServerWriteTimeout      = 5 * time.Minute
+	DefaultServerIdleTimeout       = 2 * time.Minute
+	// How long in-flight requests may finish after SIGTERM/SIGINT
+	DefaultShutdownTimeout = 30 * time.Second
+
 	// Regions
-	RegionEUDE = "eu-de"
-	RegionEUNL = "eu-nl"
+	RegionEUDE  = "eu-de"
+	RegionEUNL  = "eu-nl"
+	RegionEUCH2 = "eu-ch2"
+
+	// API domains
+	DomainOTC        = "otc.t-systems.com"
+	DomainSwissCloud = "sc.otc.t-systems.com"
+
+	// Non-metric services used by the exporter
+	ServiceIAM = "SYS.IAM"
+	ServiceRMS = "SYS.RMS"
+	ServiceCES = "SYS.CES"
+	ServiceCTS = "SYS.CTS"
+	ServiceLTS = "SYS.LTS"
+
+	// Default interval of the runtime project discovery
+	DefaultProjectRefreshInterval = 15 * time.Minute
+
+	// Default interval of the per-project namespace discovery
+	DefaultNamespaceRefreshInterval = 30 * time.Minute
+
+	// How long the result of asking CES whether a non-built-in namespace has metrics is cached
+	NamespaceValidationTTL = 10 * time.Minute
+
+	// Temporary credentials are renewed this long before they expire
+	CredentialRenewBefore = 10 * time.Minute
+	// After a failed renewal, the next attempt waits this long, doubling up to the maximum
+	CredentialRenewRetryInitial = 10 * time.Second
+	CredentialRenewRetryMax     = 2 * time.Minute
+	// Default lifetime of credentials obtained by assuming an agency
+	DefaultAgencyDurationSeconds = 3600
+
+	// File and secret-store secrets are re-read at most this often
+	DefaultSecretRefreshInterval = time.Minute
+
+	// Default path of the optional endpoint override file
+	DefaultEndpointsConfPath = "endpoints.yml"
+	// Default path of the logging config, used when logs_conf_path is not set
+	DefaultLogsConfPath = "logs.yml"
+
+	// How often clouds.yml, endpoints.yml and logs.yml are checked for changes
+	DefaultConfigWatchInterval = 30 * time.Second
 )
 
 // OBS Operations
@@ -109,6 +174,12 @@ var RetryableErrors = []string{
 	"connection reset", "connection refused",
 }
 
+// Default RMS tag/property keys identifying CCE worker nodes
+var (
+	DefaultCCEClusterKeys  = []string{"CCE-Cluster-ID", "cce-cluster-id", "metadata.cluster_id"}
+	DefaultCCENodePoolKeys = []string{"CCE-Node-Pool-ID", "cce-nodepool-id", "metadata.nodepool_id"}
+)
+
 // AllNamespaces contains all supported OTC namespaces
 var AllNamespaces = []string{
 	// Compute
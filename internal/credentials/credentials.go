@@ -0,0 +1,132 @@
+package credentials
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+)
+
+// Credentials is an access key pair, optionally temporary (with a security token).
+type Credentials struct {
+	AccessKey     string
+	SecretKey     string
+	SecurityToken string
+	// ExpiresAt is zero for permanent keys
+	ExpiresAt time.Time
+}
+
+// Temporary reports whether the credentials carry a security token.
+func (c Credentials) Temporary() bool {
+	return c.SecurityToken != ""
+}
+
+// expiresWithin reports whether the credentials expire within d.
+func (c Credentials) expiresWithin(d time.Duration) bool {
+	return !c.ExpiresAt.IsZero() && time.Until(c.ExpiresAt) < d
+}
+
+// Fetcher obtains a fresh set of temporary credentials.
+type Fetcher func() (Credentials, error)
+
+// Source holds the current credentials of an account. All SDK clients of the
+// account sign with the same Source, so renewed credentials apply to every
+// client without rebuilding it.
+type Source struct {
+	name        string
+	fetch       Fetcher
+	renewBefore time.Duration
+	// load returns the configured keys, which may be rotated externally
+	load func() Credentials
+
+	mu      sync.Mutex
+	current Credentials
+	// generation is incremented on every renewal or rotation
+	generation uint64
+	// renewing is set while a renewal runs outside the lock
+	renewing bool
+	// failures counts renewals failed in a row; retryAt delays the next attempt
+	failures int
+	retryAt  time.Time
+}
+
+// NewStatic returns a source for fixed credentials (permanent or externally supplied temporary ones).
+func NewStatic(name string, creds Credentials) *Source {
+	return &Source{name: name, current: creds}
+}
+
+// NewWatched returns a source whose keys are re-read through load, so
+// externally rotated keys (files, secret stores) apply to every client.
+func NewWatched(name string, load func() Credentials) *Source {
+	return &Source{name: name, load: load, current: load()}
+}
+
+// NewRenewing fetches credentials once and renews them renewBefore their expiry.
+func NewRenewing(name string, fetch Fetcher, renewBefore time.Duration) (*Source, error) {
+	creds, err := fetch()
+	if err != nil {
+		return nil, fmt.Errorf("obtaining temporary credentials: %w", err)
+	}
+	logs.Infof("🔑 Obtained temporary credentials for %s (expire at %s)", name, creds.ExpiresAt.Format(time.RFC3339))
+	return &Source{name: name, fetch: fetch, renewBefore: renewBefore, current: creds, generation: 1}, nil
+}
+
+// Get returns the current credentials, renewing them first if they are about to expire.
+// Only one renewal runs at a time and other callers keep using the current credentials
+// meanwhile. If renewal fails, the previous credentials are returned and renewal is
+// retried after a backoff.
+func (s *Source) Get() Credentials {
+	creds, _ := s.get()
+	return creds
+}
+
+func (s *Source) get() (Credentials, uint64) {
+	// load and fetch may call a secret store or IAM, so they run without holding
+	// the lock; signing meanwhile continues with the current credentials
+	var loaded Credentials
+	if s.load != nil {
+		loaded = s.load()
+	}
+	s.mu.Lock()
+	if s.load != nil && loaded != s.current {
+		s.current = loaded
+		s.generation++
+		logs.Infof("🔑 Credentials of %s were rotated", s.name)
+	}
+	renew := s.fetch != nil && !s.renewing && s.current.expiresWithin(s.renewBefore) && !time.Now().Before(s.retryAt)
+	if !renew {
+		defer s.mu.Unlock()
+		return s.current, s.generation
+	}
+	s.renewing = true
+	s.mu.Unlock()
+
+	creds, err := s.fetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewing = false
+	if err != nil {
+		s.failures++
+		s.retryAt = time.Now().Add(renewBackoff(s.failures))
+		logs.Errorf("❌ Renewing temporary credentials for %s failed (current ones expire at %s, next attempt at %s): %v",
+			s.name, s.current.ExpiresAt.Format(time.RFC3339), s.retryAt.Format(time.RFC3339), err)
+		return s.current, s.generation
+	}
+	s.current = creds
+	s.generation++
+	s.failures, s.retryAt = 0, time.Time{}
+	logs.Infof("🔑 Renewed temporary credentials for %s (expire at %s)", s.name, creds.ExpiresAt.Format(time.RFC3339))
+	return s.current, s.generation
+}
+
+// renewBackoff is the delay after the given number of failed renewals in a row.
+func renewBackoff(failures int) time.Duration {
+	delay := constants.CredentialRenewRetryInitial
+	for i := 1; i < failures && delay < constants.CredentialRenewRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, constants.CredentialRenewRetryMax)
+}
@@ -0,0 +1,79 @@
+package credentials
+
+import (
+	"sync"
+
+	obs "github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/impl"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/request"
+)
+
+// The SDK client builders check the concrete credential type, so clients are built
+// with a snapshot (BasicSnapshot/GlobalSnapshot) and then switched to the shared
+// credential with HcHttpClient.WithCredential.
+
+// BasicSnapshot returns project-scoped SDK credentials with the current keys.
+func (s *Source) BasicSnapshot(projectID string) *basic.Credentials {
+	creds := s.Get()
+	return &basic.Credentials{AK: creds.AccessKey, SK: creds.SecretKey, SecurityToken: creds.SecurityToken, ProjectId: projectID}
+}
+
+// GlobalSnapshot returns domain-scoped SDK credentials with the current keys.
+func (s *Source) GlobalSnapshot(domainID string) *global.Credentials {
+	creds := s.Get()
+	return &global.Credentials{AK: creds.AccessKey, SK: creds.SecretKey, SecurityToken: creds.SecurityToken, DomainId: domainID}
+}
+
+// Basic returns project-scoped SDK credentials that always sign with the current keys.
+func (s *Source) Basic(projectID string) auth.ICredential {
+	return &sharedCredential{source: s, build: func() auth.ICredential { return s.BasicSnapshot(projectID) }}
+}
+
+// Global returns domain-scoped SDK credentials that always sign with the current keys.
+func (s *Source) Global(domainID string) auth.ICredential {
+	return &sharedCredential{source: s, build: func() auth.ICredential { return s.GlobalSnapshot(domainID) }}
+}
+
+// sharedCredential signs every request with the source's current keys.
+type sharedCredential struct {
+	source *Source
+	build  func() auth.ICredential
+}
+
+// ProcessAuthParams is a no-op: project and domain IDs are always set explicitly.
+func (c *sharedCredential) ProcessAuthParams(_ *impl.DefaultHttpClient, _ string) auth.ICredential {
+	return c
+}
+
+// ProcessAuthRequest signs the request with a snapshot of the current keys.
+func (c *sharedCredential) ProcessAuthRequest(client *impl.DefaultHttpClient, req *request.DefaultHttpRequest) (*request.DefaultHttpRequest, error) {
+	return c.build().ProcessAuthRequest(client, req)
+}
+
+// ObsRefresher keeps an OBS client's keys in sync with the source.
+type ObsRefresher struct {
+	source *Source
+
+	mu         sync.Mutex
+	generation uint64
+}
+
+// NewObsRefresher creates a refresher for a client built with the source's current keys.
+func (s *Source) NewObsRefresher() *ObsRefresher {
+	_, generation := s.get()
+	return &ObsRefresher{source: s, generation: generation}
+}
+
+// Refresh pushes renewed keys into the OBS client before a request.
+func (r *ObsRefresher) Refresh(client *obs.ObsClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	creds, generation := r.source.get()
+	if generation != r.generation {
+		client.Refresh(creds.AccessKey, creds.SecretKey, creds.SecurityToken)
+		r.generation = generation
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/collector"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
 	cesModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1/model"
 )
@@ -97,7 +98,7 @@ func (ab *AlertBundle) AddFromMetricValues(ns string, metrics []collector.Metric
 		return
 	}
 	grouped := ab.groupMetricsByType(metrics)
-	service := strings.ToLower(ns[strings.LastIndex(ns, ".")+1:])
+	service := config.MetricPrefix(ns)
 	thresholds := DefaultThresholds()
 	for metricType, metricList := range grouped {
 		logs.Debugf("Creating alert group for metric type: %s (%d metrics)", metricType, len(metricList))
@@ -132,7 +133,7 @@ func (ab *AlertBundle) AddFromMetricInfo(ns string, metrics []cesModel.MetricInf
 		logs.Warnf("No CES metrics provided for alert rule creation in namespace: %s", ns)
 		return
 	}
-	service := strings.ToLower(ns[strings.LastIndex(ns, ".")+1:])
+	service := config.MetricPrefix(ns)
 	thresholds := DefaultThresholds()
 	grouped := ab.groupCESMetricsByType(metrics)
 	for metricType, metricList := range grouped {
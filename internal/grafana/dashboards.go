@@ -3,11 +3,12 @@ package grafana
 import (
 	"fmt"
 	"math/rand"
-	"strings"
 	"time"
 
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/collector"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/config"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/registry"
 	cesModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ces/v1/model"
 )
 
@@ -81,8 +82,12 @@ func generateNumericUID(length int) string {
 
 func NewDefaultDashboard(namespace string) *Dashboard {
 	logs.Infof("Creating new Grafana dashboard for namespace: %s", namespace)
+	title := fmt.Sprintf("CloudEye - %s", namespace)
+	if info, ok := registry.Lookup(namespace); ok {
+		title = fmt.Sprintf("CloudEye - %s (%s)", info.DisplayName, namespace)
+	}
 	dashboard := &Dashboard{
-		Title:  fmt.Sprintf("CloudEye - %s", namespace),
+		Title:  title,
 		UID:    generateNumericUID(12),
 		Schema: 36,
 		Templating: Templating{
@@ -135,7 +140,7 @@ func (d *Dashboard) AddFromMetricValues(ns string, metrics []collector.MetricExp
 	}
 	logs.Debugf("Grouped metrics into %d unique metric names", len(grouped))
 	x, y, panelID := 0, 0, 0
-	service := strings.ToLower(ns[strings.LastIndex(ns, ".")+1:])
+	service := config.MetricPrefix(ns)
 	for metricName, exports := range grouped {
 		if len(exports) == 0 {
 			logs.Warnf("No exports found for metric: %s", metricName)
@@ -204,7 +209,7 @@ func (d *Dashboard) AddAllMetricsGrouped(ns string, metrics []cesModel.MetricInf
 				width := 6
 				logs.Debugf("Adding gauge panel for metric: %s at position (x=%d, y=%d)", m.MetricName, x, y)
 				d.AddGaugePerResourcePanel(
-					strings.ToLower(ns[strings.LastIndex(ns, ".")+1:]),
+					config.MetricPrefix(ns),
 					m.MetricName, ns, m.Unit,
 					panelID, x, y, width,
 				)
@@ -239,7 +244,7 @@ func (d *Dashboard) AddMetricPanel(ns string, m cesModel.MetricInfoList, id, x,
 	height := 8
 	unit := m.Unit
 	panelType := determinePanelType(unit)
-	service := strings.ToLower(ns[strings.LastIndex(ns, ".")+1:])
+	service := config.MetricPrefix(ns)
 	title := fmt.Sprintf("%s (%s)", formatTitle(m.MetricName), unit)
 	logs.Debugf("Creating metric panel: ID=%d, Metric=%s, Type=%s, Unit=%s", id, m.MetricName, panelType, unit)
 	panel := Panel{
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -20,6 +21,8 @@ import (
 // LoggerConstructor wraps the main logger instance.
 type LoggerConstructor struct {
 	LogInstance logger
+	// mu guards LogInstance, which is replaced when logs.yml is reloaded
+	mu sync.RWMutex
 }
 
 // logger interface defines the expected log methods.
@@ -138,60 +141,98 @@ func (c ConfLoader) LoadData(data []byte, cfg interface{}) error {
 
 // ---- Logger Initialization ----
 func InitLog(logsConfPath string) {
-	realPath, pathErr := NormalizePath(logsConfPath)
-	if pathErr != nil {
-		fmt.Printf("Normalize log config path error: %s\n", pathErr.Error())
-		return
+	instance, err := LoadLog(logsConfPath, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fail to load logs.yml, using console logging, error: %s\n", err.Error())
+		instance = makeZapLogger(nil).WithOptions(zap.AddCallerSkip(1)).Sugar()
 	}
-	var cfg map[string][]Config
-	err := newYamlLoader().LoadFile(realPath, &cfg)
+	SetLog(instance)
+}
+
+// LoadLog builds a logger from a logs.yml without installing it, so an invalid
+// file can be rejected while the current logger keeps running. A missing file
+// yields the default console logger unless required is true.
+func LoadLog(logsConfPath string, required bool) (*zap.SugaredLogger, error) {
+	realPath, err := NormalizePath(logsConfPath)
 	if err != nil {
-		fmt.Printf("Fail to load logs.yml, error: %s\n", err.Error())
-		return
+		return nil, fmt.Errorf("normalize log config path: %w", err)
+	}
+	var cfg map[string][]Config
+	if err := newYamlLoader().LoadFile(realPath, &cfg); err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return makeZapLogger(nil).WithOptions(zap.AddCallerSkip(1)).Sugar(), nil
+		}
+		return nil, err
 	}
 	config, ok := cfg["logging"]
 	if !ok {
-		fmt.Printf("logs.yml should contain a 'logging' config.\n")
-		return
+		return nil, errors.New("logs.yml should contain a 'logging' config")
 	}
-	Logger.LogInstance = makeZapLogger(config).WithOptions(zap.AddCallerSkip(1)).Sugar()
+	for _, c := range config {
+		switch strings.ToUpper(c.Type) {
+		case "FILE", "CONSOLE":
+		default:
+			return nil, fmt.Errorf("unknown logging type: %s", c.Type)
+		}
+	}
+	return makeZapLogger(config).WithOptions(zap.AddCallerSkip(1)).Sugar(), nil
+}
+
+// SetLog installs a logger built by LoadLog and flushes the previous one.
+func SetLog(instance *zap.SugaredLogger) {
+	Logger.mu.Lock()
+	previous := Logger.LogInstance
+	Logger.LogInstance = instance
+	Logger.mu.Unlock()
+	if previous != nil {
+		if err := previous.Sync(); err != nil && !isSyncErrorIgnorable(err) {
+			fmt.Printf("Fail to sync logs, error: %s\n", err.Error())
+		}
+	}
+}
+
+// instance returns the current logger.
+func (zap *LoggerConstructor) instance() logger {
+	zap.mu.RLock()
+	defer zap.mu.RUnlock()
+	return zap.LogInstance
 }
 
 // ---- Logger Methods ----
 func (zap *LoggerConstructor) Debug(args ...interface{}) {
-	zap.LogInstance.Debug(clearLineBreaks("", args...))
+	zap.instance().Debug(clearLineBreaks("", args...))
 }
 func (zap *LoggerConstructor) Info(args ...interface{}) {
-	zap.LogInstance.Info(clearLineBreaks("", args...))
+	zap.instance().Info(clearLineBreaks("", args...))
 }
 func (zap *LoggerConstructor) Warn(args ...interface{}) {
-	zap.LogInstance.Warn(clearLineBreaks("", args...))
+	zap.instance().Warn(clearLineBreaks("", args...))
 }
 func (zap *LoggerConstructor) Error(args ...interface{}) {
-	zap.LogInstance.Error(clearLineBreaks("", args...))
+	zap.instance().Error(clearLineBreaks("", args...))
 }
 func (zap *LoggerConstructor) Fatal(args ...interface{}) {
-	zap.LogInstance.Fatal(clearLineBreaks("", args...))
+	zap.instance().Fatal(clearLineBreaks("", args...))
 }
 func (zap *LoggerConstructor) Debugf(template string, args ...interface{}) {
-	zap.LogInstance.Debugf(clearLineBreaks(template, args...))
+	zap.instance().Debugf(clearLineBreaks(template, args...))
 }
 func (zap *LoggerConstructor) Infof(template string, args ...interface{}) {
-	zap.LogInstance.Infof(clearLineBreaks(template, args...))
+	zap.instance().Infof(clearLineBreaks(template, args...))
 }
 func (zap *LoggerConstructor) Warnf(template string, args ...interface{}) {
-	zap.LogInstance.Warnf(clearLineBreaks(template, args...))
+	zap.instance().Warnf(clearLineBreaks(template, args...))
 }
 func (zap *LoggerConstructor) Errorf(template string, args ...interface{}) {
-	zap.LogInstance.Errorf(clearLineBreaks(template, args...))
+	zap.instance().Errorf(clearLineBreaks(template, args...))
 }
 func (zap *LoggerConstructor) Fatalf(template string, args ...interface{}) {
-	zap.LogInstance.Fatalf(clearLineBreaks(template, args...))
+	zap.instance().Fatalf(clearLineBreaks(template, args...))
 }
 
 // Enhanced Flush method with better error handling
 func (zap *LoggerConstructor) Flush() {
-	err := zap.LogInstance.Sync()
+	err := zap.instance().Sync()
 	if err != nil && !isSyncErrorIgnorable(err) {
 		fmt.Printf("Fail to sync logs, error: %s\n", err.Error())
 	}
@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/constants"
+)
+
+// Enrichers that add labels to the series of a namespace.
+const (
+	EnricherRMS = "rms" // resource name, tags and project labels from RMS
+	EnricherEVS = "evs" // resolves "<vm>-<device>" to the EVS disk ID and name
+	EnricherOBS = "obs" // bucket tags and info
+	EnricherCCE = "cce" // cce_cluster/cce_nodepool labels of CCE worker nodes
+)
+
+// categoryPrefix marks a namespace selector as a category, e.g. "@database".
+const categoryPrefix = "@"
+
+// CategoryAll selects every built-in namespace.
+const CategoryAll = "all"
+
+// Info describes a built-in namespace.
+type Info struct {
+	Namespace   string `json:"namespace"`
+	Category    string `json:"category"`
+	DisplayName string `json:"display_name"`
+	// ResourceDimension is the dimension used as resource_id when present
+	ResourceDimension string   `json:"resource_dimension,omitempty"`
+	Enrichers         []string `json:"enrichers"`
+}
+
+// builtin holds all built-in namespaces, in the order of constants.AllNamespaces.
+var builtin = []Info{
+	// Compute
+	{constants.NamespaceECS, constants.ServiceTypeCompute, "Elastic Cloud Server", "instance_id", []string{EnricherRMS, EnricherCCE}},
+	{constants.NamespaceAGT, constants.ServiceTypeCompute, "Elastic Cloud Server Agent", "instance_id", []string{EnricherRMS, EnricherCCE}},
+	{constants.NamespaceBMS, constants.ServiceTypeCompute, "Bare Metal Server", "instance_id", []string{EnricherRMS}},
+	{constants.NamespaceAS, constants.ServiceTypeCompute, "Auto Scaling", "AutoScalingGroup", []string{EnricherRMS}},
+
+	// Storage
+	{constants.NamespaceEVS, constants.ServiceTypeStorage, "Elastic Volume Service", "disk_name", []string{EnricherEVS, EnricherRMS, EnricherCCE}},
+	{constants.NamespaceOBS, constants.ServiceTypeStorage, "Object Storage Service", "bucket_name", []string{EnricherOBS, EnricherRMS}},
+	{constants.NamespaceSFS, constants.ServiceTypeStorage, "Scalable File Service", "share_id", []string{EnricherRMS}},
+	{constants.NamespaceEFS, constants.ServiceTypeStorage, "SFS Turbo", "efs_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceCBR, constants.ServiceTypeStorage, "Cloud Backup and Recovery", "instance_id", []string{EnricherRMS}},
+
+	// Network
+	{constants.NamespaceVPC, constants.ServiceTypeNetwork, "Elastic IP and Bandwidth", "publicip_id", []string{EnricherRMS}},
+	{constants.NamespaceELB, constants.ServiceTypeNetwork, "Elastic Load Balance", "lbaas_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceDC, constants.ServiceTypeNetwork, "Direct Connect", "direct_connect_id", []string{EnricherRMS}},
+	{constants.NamespaceNAT, constants.ServiceTypeNetwork, "NAT Gateway", "nat_gateway_id", []string{EnricherRMS}},
+	{constants.NamespaceER, constants.ServiceTypeNetwork, "Enterprise Router", "er_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceVPN, constants.ServiceTypeNetwork, "Virtual Private Network", "vpn_connection_id", []string{EnricherRMS}},
+
+	// Database
+	{constants.NamespaceRDS, constants.ServiceTypeDatabase, "Relational Database Service", "rds_cluster_id", []string{EnricherRMS}},
+	{constants.NamespaceDDS, constants.ServiceTypeDatabase, "Document Database Service", "mongodb_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceNoSQL, constants.ServiceTypeDatabase, "GeminiDB", "nosql_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceGaussDB, constants.ServiceTypeDatabase, "GaussDB(for MySQL)", "gaussdb_mysql_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceGaussDBV5, constants.ServiceTypeDatabase, "GaussDB(for openGauss)", "gaussdbv5_instance_id", []string{EnricherRMS}},
+
+	// Security
+	{constants.NamespaceWAF, constants.ServiceTypeSecurity, "Web Application Firewall", "waf_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceCFW, constants.ServiceTypeSecurity, "Cloud Firewall", "fw_instance_id", []string{EnricherRMS}},
+
+	// Application
+	{constants.NamespaceDMS, constants.ServiceTypeApplication, "Distributed Message Service", "kafka_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceDCS, constants.ServiceTypeApplication, "Distributed Cache Service", "dcs_instance_id", []string{EnricherRMS}},
+	{constants.NamespaceAPIC, constants.ServiceTypeApplication, "API Gateway", "instance_id", []string{EnricherRMS}},
+
+	// Data Analysis
+	{constants.NamespaceDWS, constants.ServiceTypeDataAnalysis, "Data Warehouse Service", "datastore_id", []string{EnricherRMS}},
+	{constants.NamespaceES, constants.ServiceTypeDataAnalysis, "Cloud Search Service", "cluster_id", []string{EnricherRMS}},
+	{constants.NamespaceDAYU, constants.ServiceTypeDataAnalysis, "Data Ingestion Service", "instance_id", []string{EnricherRMS}},
+}
+
+var byNamespace = func() map[string]Info {
+	m := make(map[string]Info, len(builtin))
+	for _, info := range builtin {
+		m[info.Namespace] = info
+	}
+	return m
+}()
+
+// Lookup returns the registry entry of a built-in namespace.
+func Lookup(namespace string) (Info, bool) {
+	info, ok := byNamespace[namespace]
+	return info, ok
+}
+
+// All returns all built-in namespaces with their metadata.
+func All() []Info {
+	return append([]Info(nil), builtin...)
+}
+
+// IsBuiltin reports whether the namespace is one of the supported OTC service namespaces.
+func IsBuiltin(namespace string) bool {
+	_, ok := byNamespace[namespace]
+	return ok
+}
+
+// HasEnricher reports whether a built-in namespace uses the given enricher by default.
+func HasEnricher(namespace, enricher string) bool {
+	for _, e := range byNamespace[namespace].Enrichers {
+		if e == enricher {
+			return true
+		}
+	}
+	return false
+}
+
+// InCategory returns the built-in namespaces of a category ("all" for every namespace).
+func InCategory(category string) []string {
+	var list []string
+	for _, info := range builtin {
+		if category == CategoryAll || info.Category == category {
+			list = append(list, info.Namespace)
+		}
+	}
+	return list
+}
+
+// Expand resolves a comma-separated namespace selection such as
+// "@database,@network,SYS.OBS" into namespaces, keeping order and dropping duplicates.
+func Expand(spec string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	add := func(ns string) {
+		if !seen[ns] {
+			seen[ns] = true
+			result = append(result, ns)
+		}
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.HasPrefix(item, categoryPrefix) {
+			add(item)
+			continue
+		}
+		category := strings.ToLower(strings.TrimPrefix(item, categoryPrefix))
+		members := InCategory(category)
+		if len(members) == 0 {
+			return nil, fmt.Errorf("unknown namespace category %q", item)
+		}
+		for _, ns := range members {
+			add(ns)
+		}
+	}
+	return result, nil
+}
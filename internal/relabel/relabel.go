@@ -0,0 +1,187 @@
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// Action is the relabeling action to perform, compatible with Prometheus relabel_configs.
+type Action string
+
+const (
+	Replace   Action = "replace"
+	Keep      Action = "keep"
+	Drop      Action = "drop"
+	HashMod   Action = "hashmod"
+	LabelMap  Action = "labelmap"
+	LabelDrop Action = "labeldrop"
+	LabelKeep Action = "labelkeep"
+
+	// MetricNameLabel holds the metric name during relabeling
+	MetricNameLabel = "__name__"
+)
+
+// relabelTarget matches label names that may contain $1 or ${name} references
+var relabelTarget = regexp.MustCompile(`^(?:(?:[a-zA-Z_]|\$(?:\{\w+\}|\w+))+\w*)+$`)
+
+// validLabelName reports whether name is a legacy (non-UTF-8) Prometheus label name.
+func validLabelName(name string) bool {
+	return model.LabelName(name).IsValidLegacy()
+}
+
+// Config is a single relabeling rule.
+type Config struct {
+	SourceLabels []string `yaml:"source_labels,flow,omitempty"`
+	Separator    string   `yaml:"separator,omitempty"`
+	Regex        string   `yaml:"regex,omitempty"`
+	Modulus      uint64   `yaml:"modulus,omitempty"`
+	TargetLabel  string   `yaml:"target_label,omitempty"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+	Action       Action   `yaml:"action,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// UnmarshalYAML applies Prometheus defaults before decoding and validates the rule.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Config
+	*c = Config{
+		Separator:   ";",
+		Regex:       "(.*)",
+		Replacement: "$1",
+		Action:      Replace,
+	}
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate checks the rule for consistency and compiles its regex.
+func (c *Config) Validate() error {
+	c.Action = Action(strings.ToLower(string(c.Action)))
+	re, err := regexp.Compile("^(?:" + c.Regex + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid relabel regex %q: %w", c.Regex, err)
+	}
+	c.regex = re
+	switch c.Action {
+	case Replace:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("relabel action %q requires target_label", c.Action)
+		}
+		if strings.Contains(c.TargetLabel, "$") {
+			if !relabelTarget.MatchString(c.TargetLabel) {
+				return fmt.Errorf("invalid target_label %q for action %q", c.TargetLabel, c.Action)
+			}
+		} else if !validLabelName(c.TargetLabel) {
+			return fmt.Errorf("invalid target_label %q for action %q", c.TargetLabel, c.Action)
+		}
+	case HashMod:
+		if c.TargetLabel == "" || c.Modulus == 0 {
+			return fmt.Errorf("relabel action %q requires target_label and a non-zero modulus", c.Action)
+		}
+		if !validLabelName(c.TargetLabel) {
+			return fmt.Errorf("invalid target_label %q for action %q", c.TargetLabel, c.Action)
+		}
+	case Keep, Drop:
+		if len(c.SourceLabels) == 0 {
+			return fmt.Errorf("relabel action %q requires source_labels", c.Action)
+		}
+	case LabelMap, LabelDrop, LabelKeep:
+		if len(c.SourceLabels) > 0 || c.TargetLabel != "" {
+			return fmt.Errorf("relabel action %q only accepts regex (and replacement for labelmap)", c.Action)
+		}
+		if c.Action == LabelMap && !relabelTarget.MatchString(c.Replacement) {
+			return fmt.Errorf("invalid replacement %q for action %q: must expand to a valid label name", c.Replacement, c.Action)
+		}
+	default:
+		return fmt.Errorf("unknown relabel action %q", c.Action)
+	}
+	return nil
+}
+
+// Process runs the rules in order on a copy of labels.
+// It returns nil if the series was dropped.
+func Process(labels map[string]string, cfgs ...*Config) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	for _, cfg := range cfgs {
+		if !cfg.apply(out) {
+			return nil
+		}
+	}
+	return out
+}
+
+func (c *Config) apply(labels map[string]string) bool {
+	values := make([]string, 0, len(c.SourceLabels))
+	for _, name := range c.SourceLabels {
+		values = append(values, labels[name])
+	}
+	val := strings.Join(values, c.Separator)
+
+	switch c.Action {
+	case Drop:
+		if c.regex.MatchString(val) {
+			return false
+		}
+	case Keep:
+		if !c.regex.MatchString(val) {
+			return false
+		}
+	case Replace:
+		indexes := c.regex.FindStringSubmatchIndex(val)
+		if indexes == nil {
+			break
+		}
+		target := string(c.regex.ExpandString(nil, c.TargetLabel, val, indexes))
+		if !validLabelName(target) {
+			break
+		}
+		res := c.regex.ExpandString(nil, c.Replacement, val, indexes)
+		if len(res) == 0 {
+			delete(labels, target)
+			break
+		}
+		labels[target] = string(res)
+	case HashMod:
+		sum := md5.Sum([]byte(val))
+		mod := binary.BigEndian.Uint64(sum[8:]) % c.Modulus
+		labels[c.TargetLabel] = fmt.Sprintf("%d", mod)
+	case LabelMap:
+		for name, value := range snapshot(labels) {
+			if c.regex.MatchString(name) {
+				labels[c.regex.ReplaceAllString(name, c.Replacement)] = value
+			}
+		}
+	case LabelDrop:
+		for name := range snapshot(labels) {
+			if c.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case LabelKeep:
+		for name := range snapshot(labels) {
+			if name != MetricNameLabel && !c.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+func snapshot(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
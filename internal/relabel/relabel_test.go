@@ -0,0 +1,149 @@
+package relabel
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func mustConfig(t *testing.T, src string) *Config {
+	t.Helper()
+	var c Config
+	if err := yaml.UnmarshalStrict([]byte(src), &c); err != nil {
+		t.Fatalf("unmarshal %q: %v", src, err)
+	}
+	return &c
+}
+
+func TestProcess(t *testing.T) {
+	input := map[string]string{
+		"__name__":      "cpu_util",
+		"namespace":     "SYS.ECS",
+		"instance_id":   "abc-123",
+		"tag_env":       "prod",
+		"resource_name": "web-1",
+	}
+	tests := []struct {
+		name  string
+		rules []string
+		want  map[string]string
+	}{
+		{
+			name:  "replace copies a value",
+			rules: []string{"{source_labels: [instance_id], target_label: id}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123", "tag_env": "prod", "resource_name": "web-1", "id": "abc-123"},
+		},
+		{
+			name:  "replace with groups and templated target",
+			rules: []string{"{source_labels: [namespace], regex: 'SYS\\.(.*)', target_label: 'svc_$1', replacement: yes}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123", "tag_env": "prod", "resource_name": "web-1", "svc_ECS": "yes"},
+		},
+		{
+			name:  "replace with empty result deletes the target",
+			rules: []string{"{source_labels: [missing], target_label: resource_name, replacement: ''}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123", "tag_env": "prod"},
+		},
+		{
+			name:  "replace without match leaves labels",
+			rules: []string{"{source_labels: [namespace], regex: 'SYS\\.RDS', target_label: db, replacement: x}"},
+			want:  input,
+		},
+		{
+			name:  "keep matching",
+			rules: []string{"{source_labels: [namespace], regex: 'SYS\\.ECS', action: keep}"},
+			want:  input,
+		},
+		{
+			name:  "keep not matching drops",
+			rules: []string{"{source_labels: [namespace], regex: 'SYS\\.RDS', action: keep}"},
+			want:  nil,
+		},
+		{
+			name:  "drop matching",
+			rules: []string{"{source_labels: [__name__, tag_env], separator: '@', regex: 'cpu_util@prod', action: drop}"},
+			want:  nil,
+		},
+		{
+			name:  "drop not matching",
+			rules: []string{"{source_labels: [tag_env], regex: dev, action: drop}"},
+			want:  input,
+		},
+		{
+			name:  "hashmod",
+			rules: []string{"{source_labels: [instance_id], modulus: 1, target_label: shard, action: hashmod}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123", "tag_env": "prod", "resource_name": "web-1", "shard": "0"},
+		},
+		{
+			name:  "labelmap",
+			rules: []string{"{regex: 'tag_(.+)', replacement: 'label_$1', action: labelmap}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123", "tag_env": "prod", "resource_name": "web-1", "label_env": "prod"},
+		},
+		{
+			name:  "labeldrop",
+			rules: []string{"{regex: 'tag_.*|resource_name', action: labeldrop}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123"},
+		},
+		{
+			name:  "labelkeep keeps the metric name",
+			rules: []string{"{regex: 'namespace', action: labelkeep}"},
+			want:  map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS"},
+		},
+		{
+			name: "rules run in order",
+			rules: []string{
+				"{regex: 'tag_(.+)', replacement: '$1', action: labelmap}",
+				"{regex: 'tag_.*', action: labeldrop}",
+				"{source_labels: [env], regex: dev, action: drop}",
+			},
+			want: map[string]string{"__name__": "cpu_util", "namespace": "SYS.ECS", "instance_id": "abc-123", "resource_name": "web-1", "env": "prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfgs []*Config
+			for _, r := range tt.rules {
+				cfgs = append(cfgs, mustConfig(t, r))
+			}
+			got := Process(input, cfgs...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Process() = %v, want %v", got, tt.want)
+			}
+			if input["tag_env"] != "prod" || len(input) != 5 {
+				t.Fatalf("Process modified its input: %v", input)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{"defaults need target_label", "{}", true},
+		{"replace literal target", "{target_label: env}", false},
+		{"replace invalid literal target", "{target_label: 'my-label'}", true},
+		{"replace templated target", "{target_label: 'tag_$1'}", false},
+		{"replace invalid templated target", "{target_label: 'x-$1'}", true},
+		{"hashmod without modulus", "{target_label: shard, action: hashmod}", true},
+		{"hashmod invalid target", "{target_label: '1shard', modulus: 2, action: hashmod}", true},
+		{"keep without source_labels", "{action: keep}", true},
+		{"labelmap", "{regex: 'tag_(.+)', replacement: 'label_$1', action: labelmap}", false},
+		{"labelmap invalid replacement", "{regex: 'tag_(.+)', replacement: 'x-$1', action: labelmap}", true},
+		{"labeldrop with target_label", "{target_label: x, action: labeldrop}", true},
+		{"action is case insensitive", "{regex: 'tag_.*', action: LabelDrop}", false},
+		{"unknown action", "{action: rename}", true},
+		{"invalid regex", "{regex: '(', target_label: x}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Config
+			err := yaml.UnmarshalStrict([]byte(tt.rule), &c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unmarshal %q: err = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+		})
+	}
+}
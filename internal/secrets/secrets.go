@@ -0,0 +1,295 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+)
+
+// Reference prefixes of the provider chain. Values without a prefix are inline.
+const (
+	prefixEnv   = "env:"
+	prefixFile  = "file:"
+	prefixStore = "secretstore:"
+)
+
+// Provider names used in logs.
+const (
+	ProviderInline = "inline"
+	ProviderEnv    = "env"
+	ProviderFile   = "file"
+	ProviderStore  = "secretstore"
+)
+
+// envRefPattern matches the legacy "${VAR}" syntax
+var envRefPattern = regexp.MustCompile(`^\$\{(\w+)\}$`)
+
+// loader reads the current value of a reference.
+type loader func() (string, error)
+
+// Resolver resolves secret references through the provider chain:
+// inline values, environment variables ("${VAR}", "env:VAR" or legacy bare "OS_*"),
+// files ("file:/path", e.g. mounted Kubernetes Secrets) and an HTTP secret store ("secretstore:key").
+type Resolver struct {
+	store           *httpStore
+	refreshInterval time.Duration
+}
+
+// NewResolver creates a resolver; store may be nil when no secret store is configured.
+// Watched values are re-read at most every refreshInterval.
+func NewResolver(store *StoreConfig, refreshInterval time.Duration) (*Resolver, error) {
+	r := &Resolver{refreshInterval: refreshInterval}
+	if store != nil && store.URL != "" {
+		// The store token itself may come from the environment or a file
+		token, err := r.Resolve(store.Token)
+		if err != nil {
+			return nil, fmt.Errorf("secret_store.token: %w", err)
+		}
+		r.store = newHTTPStore(*store, token)
+	}
+	return r, nil
+}
+
+// Resolve reads a reference once.
+func (r *Resolver) Resolve(ref string) (string, error) {
+	v, err := r.Watch(ref)
+	if err != nil {
+		return "", err
+	}
+	return v.Get(), nil
+}
+
+// Watch resolves a reference into a Value that picks up rotated secrets.
+func (r *Resolver) Watch(ref string) (*Value, error) {
+	provider, load, err := r.loaderFor(ref)
+	if err != nil {
+		return nil, err
+	}
+	v := &Value{ref: ref, provider: provider, load: load, interval: r.refreshInterval}
+	if load == nil {
+		v.current = ref
+		return v, nil
+	}
+	if v.current, err = load(); err != nil {
+		return nil, fmt.Errorf("%s secret %q: %w", provider, ref, err)
+	}
+	v.checked = time.Now()
+	if provider == ProviderEnv {
+		// The environment of a running process does not change
+		v.load = nil
+	}
+	return v, nil
+}
+
+func (r *Resolver) loaderFor(ref string) (string, loader, error) {
+	switch {
+	case strings.HasPrefix(ref, prefixEnv):
+		return ProviderEnv, envLoader(strings.TrimPrefix(ref, prefixEnv)), nil
+	case envRefPattern.MatchString(ref):
+		return ProviderEnv, envLoader(envRefPattern.FindStringSubmatch(ref)[1]), nil
+	case strings.HasPrefix(ref, "OS_") && strings.ToUpper(ref) == ref:
+		// Legacy: bare OS_* names fall back to the literal value when unset
+		return ProviderEnv, func() (string, error) {
+			if v := os.Getenv(ref); v != "" {
+				return v, nil
+			}
+			return ref, nil
+		}, nil
+	case strings.HasPrefix(ref, prefixFile):
+		path := strings.TrimPrefix(ref, prefixFile)
+		return ProviderFile, func() (string, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(data)), nil
+		}, nil
+	case strings.HasPrefix(ref, prefixStore):
+		if r.store == nil {
+			return "", nil, fmt.Errorf("secret %q references a secret store, but global.secret_store.url is not set", ref)
+		}
+		key := strings.TrimPrefix(ref, prefixStore)
+		return ProviderStore, func() (string, error) { return r.store.get(key) }, nil
+	}
+	return ProviderInline, nil, nil
+}
+
+// IsReference reports whether a value refers to a secret (env, file, secret store)
+// rather than containing it.
+func IsReference(ref string) bool {
+	return ProviderOf(ref) != ProviderInline
+}
+
+// ProviderOf returns the name of the provider a reference is resolved by.
+func ProviderOf(ref string) string {
+	if strings.HasPrefix(ref, prefixStore) {
+		return ProviderStore
+	}
+	provider, _, _ := (&Resolver{}).loaderFor(ref)
+	return provider
+}
+
+// envLoader reads an environment variable; an unset variable is an error, so a
+// missing credential fails at startup rather than at the first cloud API call.
+func envLoader(name string) loader {
+	return func() (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	}
+}
+
+// Value is a resolved secret. Values from files and the secret store are re-read
+// when older than the refresh interval, so rotated secrets are picked up without a restart.
+type Value struct {
+	ref      string
+	provider string
+	load     loader
+	interval time.Duration
+
+	mu      sync.Mutex
+	current string
+	checked time.Time
+	// refreshing is set while a re-read runs outside the lock
+	refreshing bool
+}
+
+// Get returns the current value, re-reading it first if it is due. Like Group.Get,
+// the re-read runs without holding the lock. If re-reading fails, the previous
+// value is kept.
+func (v *Value) Get() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.Lock()
+	if v.load == nil || v.refreshing || time.Since(v.checked) < v.interval {
+		defer v.mu.Unlock()
+		return v.current
+	}
+	v.refreshing = true
+	v.mu.Unlock()
+
+	latest, err := v.load()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.refreshing = false
+	v.checked = time.Now()
+	if err != nil {
+		logs.Warnf("⚠️ Could not re-read %s secret %q, keeping the previous value: %v", v.provider, v.ref, err)
+		return v.current
+	}
+	if latest != v.current {
+		logs.Infof("🔄 %s secret %q changed, using the rotated value", v.provider, v.ref)
+		v.current = latest
+	}
+	return v.current
+}
+
+// Provider returns the name of the provider the value comes from.
+func (v *Value) Provider() string {
+	return v.provider
+}
+
+// Group is a set of secrets that rotate together, such as an access key, secret key
+// and security token. All members are re-read in one pass and replaced together, so
+// callers never see a new access key with an old secret key.
+type Group struct {
+	refs      []string
+	providers []string
+	loads     []loader
+	interval  time.Duration
+
+	mu      sync.Mutex
+	current []string
+	checked time.Time
+	// refreshing is set while a re-read runs outside the lock
+	refreshing bool
+}
+
+// WatchGroup resolves refs into a Group; inline refs keep their value.
+func (r *Resolver) WatchGroup(refs ...string) (*Group, error) {
+	g := &Group{refs: refs, interval: r.refreshInterval}
+	for _, ref := range refs {
+		provider, load, err := r.loaderFor(ref)
+		if err != nil {
+			return nil, err
+		}
+		g.providers = append(g.providers, provider)
+		g.loads = append(g.loads, load)
+	}
+	values, err := g.read()
+	if err != nil {
+		return nil, err
+	}
+	g.current, g.checked = values, time.Now()
+	for i, provider := range g.providers {
+		if provider == ProviderEnv {
+			// The environment of a running process does not change
+			value := values[i]
+			g.loads[i] = func() (string, error) { return value, nil }
+		}
+	}
+	return g, nil
+}
+
+// read loads every member; it fails if any member cannot be read.
+func (g *Group) read() ([]string, error) {
+	values := make([]string, len(g.refs))
+	for i, load := range g.loads {
+		if load == nil {
+			values[i] = g.refs[i]
+			continue
+		}
+		v, err := load()
+		if err != nil {
+			return nil, fmt.Errorf("%s secret %q: %w", g.providers[i], g.refs[i], err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// Get returns the current values in the order of the refs, re-reading them first
+// if they are due. Only one re-read runs at a time, without holding the lock, and
+// other callers get the current values meanwhile. If any member cannot be re-read,
+// all previous values are kept.
+func (g *Group) Get() []string {
+	g.mu.Lock()
+	if g.refreshing || time.Since(g.checked) < g.interval {
+		defer g.mu.Unlock()
+		return g.current
+	}
+	g.refreshing = true
+	g.mu.Unlock()
+
+	latest, err := g.read()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshing = false
+	g.checked = time.Now()
+	if err != nil {
+		logs.Warnf("⚠️ Could not re-read a secret of a group, keeping the previous values: %v", err)
+		return g.current
+	}
+	for i := range latest {
+		if latest[i] != g.current[i] {
+			logs.Infof("🔄 %s secret %q changed, using the rotated values", g.providers[i], g.refs[i])
+		}
+	}
+	g.current = latest
+	return g.current
+}
+
+// Provider returns the name of the provider member i comes from.
+func (g *Group) Provider(i int) string {
+	return g.providers[i]
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StoreConfig configures the HTTP secret store. A secret "secretstore:<key>" is read
+// with GET <url>/<key>; the response is either the raw value or JSON {"value": "..."}.
+type StoreConfig struct {
+	URL string `yaml:"url"`
+	// Token is sent as "Authorization: Bearer <token>" (may be "env:..." or "file:...")
+	Token          string `yaml:"token,omitempty"`
+	TimeoutSeconds int    `yaml:"timeout_seconds,omitempty"`
+
+	// Transport carries the requests, so the proxy and TLS settings of the
+	// cloud API clients apply; nil uses http.DefaultTransport
+	Transport http.RoundTripper `yaml:"-"`
+}
+
+type httpStore struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newHTTPStore(cfg StoreConfig, token string) *httpStore {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &httpStore{
+		baseURL: strings.TrimSuffix(cfg.URL, "/"),
+		token:   token,
+		client:  &http.Client{Transport: cfg.Transport, Timeout: timeout},
+	}
+}
+
+func (s *httpStore) get(key string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.baseURL+"/"+url.PathEscape(key), nil)
+	if err != nil {
+		return "", err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret store returned %s", resp.Status)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var payload struct {
+			Value *string `json:"value"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", fmt.Errorf("invalid secret store response: %w", err)
+		}
+		if payload.Value == nil {
+			return "", fmt.Errorf("secret store response has no \"value\" field")
+		}
+		return *payload.Value, nil
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against for unknown users, so they take as long as known ones.
+var dummyHash = []byte("$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi")
+
+// basicAuthCacheSize bounds the remembered bcrypt results
+const basicAuthCacheSize = 100
+
+// basicAuthCache remembers bcrypt results, since a bcrypt comparison on every
+// scrape is expensive. Keys are hashes of user, hash and password.
+type basicAuthCache struct {
+	mu      sync.Mutex
+	results map[[32]byte]bool
+}
+
+func newBasicAuthCache() *basicAuthCache {
+	return &basicAuthCache{results: make(map[[32]byte]bool)}
+}
+
+func (c *basicAuthCache) check(user, password, hash string) bool {
+	key := sha256.Sum256([]byte(user + "\x00" + hash + "\x00" + password))
+	c.mu.Lock()
+	ok, cached := c.results[key]
+	c.mu.Unlock()
+	if cached {
+		return ok
+	}
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		ok = false
+	} else {
+		ok = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	c.mu.Lock()
+	if len(c.results) >= basicAuthCacheSize {
+		c.results = make(map[[32]byte]bool)
+	}
+	c.results[key] = ok
+	c.mu.Unlock()
+	return ok
+}
+
+// authenticate returns the method a request authenticated with, or "".
+func (c *WebConfig) authenticate(r *http.Request, policy PathPolicy) string {
+	if policy.allows(AuthClientCert) && c.verifiesClientCerts() && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return AuthClientCert
+	}
+	auth := r.Header.Get("Authorization")
+	if policy.allows(AuthBearer) && len(c.BearerTokens) > 0 && strings.HasPrefix(auth, "Bearer ") {
+		token := []byte(strings.TrimPrefix(auth, "Bearer "))
+		for _, t := range c.BearerTokens {
+			if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+				return AuthBearer
+			}
+		}
+	}
+	if policy.allows(AuthBasic) && len(c.BasicAuthUsers) > 0 {
+		if user, password, ok := r.BasicAuth(); ok && c.basicAuth.check(user, password, c.BasicAuthUsers[user]) {
+			return AuthBasic
+		}
+	}
+	return ""
+}
+
+// Authenticate wraps a handler with the web config of the current configuration:
+// response headers, and authentication according to the path policies. current may
+// return nil when no web config is set.
+func Authenticate(current func() *WebConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := current()
+		if cfg == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for name, value := range cfg.HTTPServerConfig.Headers {
+			w.Header().Set(name, value)
+		}
+		policy := cfg.policy(r.URL.Path)
+		if policy.Auth == "none" || !cfg.authConfigured() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if cfg.authenticate(r, policy) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logs.Debugf("Rejected unauthenticated request for %s from %s", r.URL.Path, r.RemoteAddr)
+		if policy.allows(AuthBasic) && len(cfg.BasicAuthUsers) > 0 {
+			w.Header().Add("WWW-Authenticate", `Basic realm="otc-cloudeye-exporter"`)
+		}
+		if policy.allows(AuthBearer) && len(cfg.BearerTokens) > 0 {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="otc-cloudeye-exporter"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
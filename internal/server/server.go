@@ -1,11 +1,15 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 )
 
 // Config holds server-level configuration
@@ -15,6 +19,26 @@ type Config struct {
 	HTTPSPort   string // e.g., ":8443"
 	CertFile    string
 	KeyFile     string
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// TLS serves HTTPPort over TLS and sets the version, cipher and client
+	// certificate settings of the HTTPS server (from the web config file)
+	TLS          *tls.Config
+	DisableHTTP2 bool
+}
+
+// Server runs the HTTP server and, if configured, the HTTPS server.
+type Server struct {
+	servers []*http.Server
+	// tlsServer is the HTTPS server in servers, if any
+	tlsServer *http.Server
+	// mainTLS is set when the HTTP port serves TLS
+	mainTLS bool
+	cfg     Config
 }
 
 // fileExists returns true if the file exists and is not a directory
@@ -23,35 +47,92 @@ func fileExists(path string) bool {
 	return err == nil && !info.IsDir()
 }
 
-// Start launches both HTTP and HTTPS servers (HTTPS only if certs are present)
-func Start(cfg Config, handler http.Handler) error {
-	errs := make(chan error, 2)
-
-	// 1. Start HTTP server
-	go func() {
-		logs.Infof("🌐 Starting HTTP server on %s", cfg.HTTPPort)
-		err := http.ListenAndServe(cfg.HTTPPort, handler)
-		errs <- fmt.Errorf("HTTP server error: %w", err)
-	}()
-
-	// 2. Conditionally start HTTPS server
+// New creates the HTTP server and the HTTPS server (only if certs are present).
+func New(cfg Config, handler http.Handler) *Server {
+	s := &Server{cfg: cfg}
+	primary := s.newServer(cfg.HTTPPort, handler)
+	if cfg.TLS != nil {
+		// The certificate is part of the TLS config
+		primary.TLSConfig = cfg.TLS
+		s.mainTLS = true
+	}
+	s.servers = append(s.servers, primary)
 	if cfg.EnableHTTPS {
 		if !fileExists(cfg.CertFile) || !fileExists(cfg.KeyFile) {
-			logs.Warnf("HTTPS enabled, but cert file (%s) or key file (%s) does not exist. Skipping HTTPS server.", cfg.CertFile, cfg.KeyFile)
+			logs.Warnf("HTTPS enabled, but the tls_cert or tls_key file does not exist. Skipping HTTPS server.")
 		} else {
-			go func() {
-				logs.Infof("🔐 Starting HTTPS server on %s", cfg.HTTPSPort)
-				server := &http.Server{
-					Addr:      cfg.HTTPSPort,
-					Handler:   handler,
-					TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12},
-				}
-				err := server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
-				errs <- fmt.Errorf("HTTPS server error: %w", err)
-			}()
+			s.tlsServer = s.newServer(cfg.HTTPSPort, handler)
+			s.tlsServer.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			if cfg.TLS != nil {
+				// tls_cert/tls_key replace the web config's certificate
+				s.tlsServer.TLSConfig = cfg.TLS.Clone()
+			}
+			s.servers = append(s.servers, s.tlsServer)
 		}
 	}
+	return s
+}
+
+func (s *Server) newServer(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+	}
+	if s.cfg.DisableHTTP2 {
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return srv
+}
 
-	// Wait for first error
-	return <-errs
+// Start launches the servers. The returned channel receives the first error of
+// a server that stopped for another reason than Shutdown.
+func (s *Server) Start() <-chan error {
+	errs := make(chan error, len(s.servers))
+	for _, srv := range s.servers {
+		go func(srv *http.Server) {
+			var err error
+			if srv == s.tlsServer {
+				logs.Infof("🔐 Starting HTTPS server on %s", srv.Addr)
+				err = srv.ListenAndServeTLS(s.cfg.CertFile, s.cfg.KeyFile)
+			} else if s.mainTLS {
+				logs.Infof("🔐 Starting HTTPS server on %s (web config)", srv.Addr)
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				logs.Infof("🌐 Starting HTTP server on %s", srv.Addr)
+				err = srv.ListenAndServe()
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return
+			}
+			if srv == s.tlsServer || s.mainTLS {
+				errs <- fmt.Errorf("HTTPS server error: %w", err)
+			} else {
+				errs <- fmt.Errorf("HTTP server error: %w", err)
+			}
+		}(srv)
+	}
+	return errs
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests until ctx
+// is done; requests still running then are cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.servers))
+	for i, srv := range s.servers {
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				errs[i] = fmt.Errorf("%s: %w", srv.Addr, err)
+			}
+		}(i, srv)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
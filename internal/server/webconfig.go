@@ -0,0 +1,337 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// WebConfig is a Prometheus exporter-toolkit compatible web config file, extended
+// with bearer tokens and per-path policies.
+type WebConfig struct {
+	TLSServerConfig  *TLSServerConfig `yaml:"tls_server_config"`
+	HTTPServerConfig HTTPServerConfig `yaml:"http_server_config"`
+	// BasicAuthUsers maps user names to bcrypt password hashes
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+
+	// BearerTokens are accepted as "Authorization: Bearer <token>"; each may be a
+	// secret reference ("file:/path", "env:VAR")
+	BearerTokens []string `yaml:"bearer_tokens"`
+	// PathPolicies decide per path whether and how requests must authenticate.
+	// The first matching policy applies; unmatched paths require authentication.
+	PathPolicies []PathPolicy `yaml:"path_policies"`
+
+	basicAuth *basicAuthCache
+}
+
+// TLSServerConfig serves the exporter's main port over TLS.
+type TLSServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	// ClientAllowedSans restricts verified client certificates to these SANs
+	ClientAllowedSans        []string `yaml:"client_allowed_sans"`
+	MinVersion               string   `yaml:"min_version"`
+	MaxVersion               string   `yaml:"max_version"`
+	CipherSuites             []string `yaml:"cipher_suites"`
+	CurvePreferences         []string `yaml:"curve_preferences"`
+	PreferServerCipherSuites bool     `yaml:"prefer_server_cipher_suites"`
+}
+
+// HTTPServerConfig holds HTTP settings of the web config file.
+type HTTPServerConfig struct {
+	HTTP2   *bool             `yaml:"http2"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// PathPolicy applies to requests whose path is listed; a path ending in "*" matches a prefix.
+type PathPolicy struct {
+	Paths []string `yaml:"paths"`
+	// Auth is "required" (default) or "none"
+	Auth string `yaml:"auth"`
+	// Methods restricts the accepted authentication methods (basic, bearer, client_cert)
+	Methods []string `yaml:"methods"`
+}
+
+// Authentication methods of a PathPolicy
+const (
+	AuthBasic      = "basic"
+	AuthBearer     = "bearer"
+	AuthClientCert = "client_cert"
+)
+
+// probePaths stay unauthenticated when a web config sets no path_policies.
+var probePaths = []string{"/health", "/healthz", "/ready", "/readyz", "/live", "/livez"}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+var curves = map[string]tls.CurveID{
+	"CurveP256": tls.CurveP256,
+	"CurveP384": tls.CurveP384,
+	"CurveP521": tls.CurveP521,
+	"X25519":    tls.X25519,
+}
+
+// LoadWebConfig reads and validates a web config file. Relative file paths in it
+// are relative to the file's directory. An empty path returns nil (no auth, no TLS).
+func LoadWebConfig(path string) (*WebConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg WebConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	if t := cfg.TLSServerConfig; t != nil {
+		t.CertFile = relativeTo(dir, t.CertFile)
+		t.KeyFile = relativeTo(dir, t.KeyFile)
+		t.ClientCAFile = relativeTo(dir, t.ClientCAFile)
+	}
+	resolver, err := secrets.NewResolver(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i, token := range cfg.BearerTokens {
+		if cfg.BearerTokens[i], err = resolver.Resolve(token); err != nil {
+			return nil, fmt.Errorf("bearer_tokens[%d]: %w", i, err)
+		}
+		if cfg.BearerTokens[i] == "" {
+			return nil, fmt.Errorf("bearer_tokens[%d]: is empty", i)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.basicAuth = newBasicAuthCache()
+	return &cfg, nil
+}
+
+func relativeTo(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (c *WebConfig) validate() error {
+	var errs []error
+	for user, hash := range c.BasicAuthUsers {
+		if !strings.HasPrefix(hash, "$2") {
+			errs = append(errs, fmt.Errorf("basic_auth_users.%s: must be a bcrypt hash", user))
+		}
+	}
+	for i, p := range c.PathPolicies {
+		if len(p.Paths) == 0 {
+			errs = append(errs, fmt.Errorf("path_policies[%d].paths: is required", i))
+		}
+		if p.Auth != "" && p.Auth != "required" && p.Auth != "none" {
+			errs = append(errs, fmt.Errorf("path_policies[%d].auth: must be required or none, got %q", i, p.Auth))
+		}
+		for _, m := range p.Methods {
+			if m != AuthBasic && m != AuthBearer && m != AuthClientCert {
+				errs = append(errs, fmt.Errorf("path_policies[%d].methods: unknown method %q (basic, bearer, client_cert)", i, m))
+			}
+		}
+	}
+	if c.TLSServerConfig != nil {
+		if _, err := c.TLSServerConfig.build(); err != nil {
+			errs = append(errs, fmt.Errorf("tls_server_config: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TLSConfig returns the server TLS settings, or nil when the main port serves plain HTTP.
+func (c *WebConfig) TLSConfig() (*tls.Config, error) {
+	if c == nil || c.TLSServerConfig == nil {
+		return nil, nil
+	}
+	return c.TLSServerConfig.build()
+}
+
+// build turns the settings into a tls.Config with the server certificate loaded.
+func (t *TLSServerConfig) build() (*tls.Config, error) {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, fmt.Errorf("cert_file and key_file are required")
+	}
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates:             []tls.Certificate{cert},
+		MinVersion:               tls.VersionTLS12,
+		PreferServerCipherSuites: t.PreferServerCipherSuites,
+	}
+	if t.MinVersion != "" {
+		if cfg.MinVersion, err = parseTLSVersion(t.MinVersion); err != nil {
+			return nil, fmt.Errorf("min_version: %w", err)
+		}
+	}
+	if t.MaxVersion != "" {
+		if cfg.MaxVersion, err = parseTLSVersion(t.MaxVersion); err != nil {
+			return nil, fmt.Errorf("max_version: %w", err)
+		}
+	}
+	for _, name := range t.CipherSuites {
+		id, err := cipherSuite(name)
+		if err != nil {
+			return nil, fmt.Errorf("cipher_suites: %w", err)
+		}
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+	for _, name := range t.CurvePreferences {
+		curve, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("curve_preferences: unknown curve %q", name)
+		}
+		cfg.CurvePreferences = append(cfg.CurvePreferences, curve)
+	}
+	var ok bool
+	if cfg.ClientAuth, ok = clientAuthTypes[t.ClientAuthType]; !ok {
+		return nil, fmt.Errorf("client_auth_type: unknown type %q", t.ClientAuthType)
+	}
+	if t.ClientCAFile != "" {
+		data, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("client_ca_file: %w", err)
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("client_ca_file: %s contains no PEM certificate", t.ClientCAFile)
+		}
+	}
+	verifies := cfg.ClientAuth == tls.VerifyClientCertIfGiven || cfg.ClientAuth == tls.RequireAndVerifyClientCert
+	if verifies && cfg.ClientCAs == nil {
+		return nil, fmt.Errorf("client_ca_file is required with client_auth_type %s", t.ClientAuthType)
+	}
+	if len(t.ClientAllowedSans) > 0 {
+		if !verifies {
+			return nil, fmt.Errorf("client_allowed_sans requires client_auth_type VerifyClientCertIfGiven or RequireAndVerifyClientCert")
+		}
+		cfg.VerifyPeerCertificate = allowedSans(t.ClientAllowedSans)
+	}
+	return cfg, nil
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	version, ok := tlsVersions[v]
+	if !ok {
+		return 0, fmt.Errorf("must be one of TLS10, TLS11, TLS12 or TLS13, got %q", v)
+	}
+	return version, nil
+}
+
+func cipherSuite(name string) (uint16, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, nil
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.Name == name {
+			return 0, fmt.Errorf("%s is insecure", name)
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher suite %q", name)
+}
+
+// allowedSans rejects verified client certificates without one of the SANs.
+func allowedSans(sans []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, chains [][]*x509.Certificate) error {
+		if len(chains) == 0 {
+			return nil // no certificate given; path policies decide
+		}
+		cert := chains[0][0]
+		var names []string
+		names = append(names, cert.DNSNames...)
+		names = append(names, cert.EmailAddresses...)
+		for _, ip := range cert.IPAddresses {
+			names = append(names, ip.String())
+		}
+		for _, uri := range cert.URIs {
+			names = append(names, uri.String())
+		}
+		for _, name := range names {
+			for _, allowed := range sans {
+				if name == allowed {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("client certificate SANs %v are not allowed", names)
+	}
+}
+
+// authConfigured reports whether any authentication method is set up.
+func (c *WebConfig) authConfigured() bool {
+	return len(c.BasicAuthUsers) > 0 || len(c.BearerTokens) > 0 || c.verifiesClientCerts()
+}
+
+func (c *WebConfig) verifiesClientCerts() bool {
+	if c.TLSServerConfig == nil {
+		return false
+	}
+	t := clientAuthTypes[c.TLSServerConfig.ClientAuthType]
+	return t == tls.VerifyClientCertIfGiven || t == tls.RequireAndVerifyClientCert
+}
+
+// policy returns the policy of a request path.
+func (c *WebConfig) policy(path string) PathPolicy {
+	policies := c.PathPolicies
+	if len(policies) == 0 {
+		policies = []PathPolicy{{Paths: probePaths, Auth: "none"}}
+	}
+	for _, p := range policies {
+		for _, pattern := range p.Paths {
+			if pattern == path || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))) {
+				return p
+			}
+		}
+	}
+	return PathPolicy{Auth: "required"}
+}
+
+// allows reports whether a policy accepts an authentication method.
+func (p PathPolicy) allows(method string) bool {
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abdo-farag/otc-cloudeye-exporter/internal/logs"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	logs.SetLog(zap.NewNop().Sugar())
+}
+
+func testWebConfig(t *testing.T, policies []PathPolicy) *WebConfig {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &WebConfig{
+		TLSServerConfig: &TLSServerConfig{ClientAuthType: "VerifyClientCertIfGiven"},
+		HTTPServerConfig: HTTPServerConfig{
+			Headers: map[string]string{"X-Content-Type-Options": "nosniff"},
+		},
+		BasicAuthUsers: map[string]string{"prometheus": string(hash)},
+		BearerTokens:   []string{"token-1", "token-2"},
+		PathPolicies:   policies,
+		basicAuth:      newBasicAuthCache(),
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	policies := []PathPolicy{
+		{Paths: []string{"/healthz", "/ready"}, Auth: "none"},
+		{Paths: []string{"/metrics"}, Methods: []string{AuthBearer, AuthClientCert}},
+		{Paths: []string{"/debug/*"}, Methods: []string{AuthBasic}},
+	}
+	tests := []struct {
+		name       string
+		policies   []PathPolicy
+		path       string
+		basic      []string
+		bearer     string
+		clientCert bool
+		want       int
+		challenges []string
+	}{
+		{name: "open path", policies: policies, path: "/healthz", want: http.StatusOK},
+		{name: "bearer token", policies: policies, path: "/metrics", bearer: "token-2", want: http.StatusOK},
+		{name: "wrong bearer token", policies: policies, path: "/metrics", bearer: "token-3", want: http.StatusUnauthorized,
+			challenges: []string{`Bearer realm="otc-cloudeye-exporter"`}},
+		{name: "client certificate", policies: policies, path: "/metrics", clientCert: true, want: http.StatusOK},
+		{name: "basic auth not allowed by policy", policies: policies, path: "/metrics", basic: []string{"prometheus", "secret"}, want: http.StatusUnauthorized,
+			challenges: []string{`Bearer realm="otc-cloudeye-exporter"`}},
+		{name: "prefix policy with basic auth", policies: policies, path: "/debug/pprof", basic: []string{"prometheus", "secret"}, want: http.StatusOK},
+		{name: "prefix policy rejects bearer", policies: policies, path: "/debug/pprof", bearer: "token-1", want: http.StatusUnauthorized,
+			challenges: []string{`Basic realm="otc-cloudeye-exporter"`}},
+		{name: "wrong password", policies: policies, path: "/debug/pprof", basic: []string{"prometheus", "wrong"}, want: http.StatusUnauthorized},
+		{name: "unknown user", policies: policies, path: "/debug/pprof", basic: []string{"grafana", "secret"}, want: http.StatusUnauthorized},
+		{name: "unmatched path requires any method", policies: policies, path: "/other", basic: []string{"prometheus", "secret"}, want: http.StatusOK},
+		{name: "unmatched path without credentials", policies: policies, path: "/other", want: http.StatusUnauthorized,
+			challenges: []string{`Basic realm="otc-cloudeye-exporter"`, `Bearer realm="otc-cloudeye-exporter"`}},
+		{name: "default policy keeps probes open", path: "/readyz", want: http.StatusOK},
+		{name: "default policy protects metrics", path: "/metrics", want: http.StatusUnauthorized},
+		{name: "default policy accepts bearer", path: "/metrics", bearer: "token-1", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testWebConfig(t, tt.policies)
+			handler := Authenticate(func() *WebConfig { return cfg }, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.basic != nil {
+				req.SetBasicAuth(tt.basic[0], tt.basic[1])
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.clientCert {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("header X-Content-Type-Options = %q, want nosniff", got)
+			}
+			if tt.challenges != nil {
+				if got := rec.Header().Values("WWW-Authenticate"); strings.Join(got, ",") != strings.Join(tt.challenges, ",") {
+					t.Errorf("WWW-Authenticate = %q, want %q", got, tt.challenges)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithoutWebConfig(t *testing.T) {
+	handler := Authenticate(func() *WebConfig { return nil }, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestLoadWebConfig(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WEB_TEST_TOKEN", "from-env")
+	t.Setenv("WEB_TEST_EMPTY_TOKEN", "")
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		tokens  []string
+	}{
+		{name: "empty file", content: ""},
+		{name: "token references", content: "bearer_tokens: [inline, 'file:" + tokenFile + "', 'env:WEB_TEST_TOKEN']\n",
+			tokens: []string{"inline", "from-file", "from-env"}},
+		{name: "empty token", content: "bearer_tokens: ['env:WEB_TEST_EMPTY_TOKEN']\n", wantErr: "bearer_tokens[0]: is empty"},
+		{name: "unset token variable", content: "bearer_tokens: ['env:WEB_TEST_UNSET_TOKEN']\n", wantErr: "environment variable WEB_TEST_UNSET_TOKEN is not set"},
+		{name: "unknown field", content: "basic_auth: {}\n", wantErr: "field basic_auth not found"},
+		{name: "plain text password", content: "basic_auth_users: {prometheus: secret}\n", wantErr: "basic_auth_users.prometheus: must be a bcrypt hash"},
+		{name: "policy without paths", content: "path_policies: [{auth: none}]\n", wantErr: "path_policies[0].paths: is required"},
+		{name: "unknown auth", content: "path_policies: [{paths: [/x], auth: optional}]\n", wantErr: "path_policies[0].auth: must be required or none"},
+		{name: "unknown method", content: "path_policies: [{paths: [/x], methods: [oauth]}]\n", wantErr: `unknown method "oauth"`},
+		{name: "tls without certificate", content: "tls_server_config: {}\n", wantErr: "cert_file and key_file are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "web.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadWebConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(cfg.BearerTokens, ",") != strings.Join(tt.tokens, ",") {
+				t.Errorf("bearer tokens = %q, want %q", cfg.BearerTokens, tt.tokens)
+			}
+		})
+	}
+}